@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// AuditRecord says that a value was stored, when and by whom, without holding on to the value itself.
+type AuditRecord struct {
+	ID     string // what the DB stored the value as
+	Time   time.Time
+	Hash   string // hex sha256 of the value, enough to check a value against the trail
+	Caller string // file:line of the code that called StoreToDB
+}
+
+type AuditSink interface {
+	Audit(AuditRecord)
+}
+
+// PrintAuditSink prints every record, the simplest sink there is.
+type PrintAuditSink struct{}
+
+func (PrintAuditSink) Audit(rec AuditRecord) {
+	fmt.Printf("audit: %s stored %s as %s from %s\n", rec.Time.Format(time.RFC3339), rec.Hash[:12], rec.ID, rec.Caller)
+}
+
+type auditedDB struct {
+	db   DB
+	sink AuditSink
+}
+
+// WithAudit reports every value db stored successfully to sink, failed stores leave no trace.
+func WithAudit(db DB, sink AuditSink) DB {
+	return &auditedDB{db: db, sink: sink}
+}
+
+func (a *auditedDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok { // 0 is this function, 1 whoever called it
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	id, err := a.db.StoreToDB(ctx, value)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	a.sink.Audit(AuditRecord{ID: id, Time: time.Now(), Hash: hex.EncodeToString(sum[:]), Caller: caller})
+	return id, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sliceAuditSink keeps every record it's given.
+type sliceAuditSink []AuditRecord
+
+func (s *sliceAuditSink) Audit(rec AuditRecord) { *s = append(*s, rec) }
+
+func TestWithAudit(t *testing.T) {
+	errDown := errors.New("db is down")
+	tests := []struct {
+		name        string
+		err         error
+		wantRecords int
+	}{
+		{"success", nil, 1},
+		{"failure", errDown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sink sliceAuditSink
+			db := WithAudit(&recordingDB{id: "42", err: tt.err}, &sink)
+
+			before := time.Now()
+			id, err := db.StoreToDB(context.Background(), "hello") // the caller the record should point at
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if len(sink) != tt.wantRecords {
+				t.Fatalf("%d audit records, want %d", len(sink), tt.wantRecords)
+			}
+			if tt.wantRecords == 0 {
+				return
+			}
+
+			rec := sink[0]
+			sum := sha256.Sum256([]byte("hello"))
+			if rec.ID != id || rec.ID != "42" {
+				t.Errorf("ID = %q, want %q", rec.ID, "42")
+			}
+			if rec.Hash != hex.EncodeToString(sum[:]) {
+				t.Errorf("Hash = %q, want the sha256 of the value", rec.Hash)
+			}
+			if rec.Time.Before(before) || rec.Time.After(time.Now()) {
+				t.Errorf("Time = %v, want the time of the store", rec.Time)
+			}
+			if !strings.Contains(rec.Caller, "audit_test.go:") {
+				t.Errorf("Caller = %q, want this test's file:line", rec.Caller)
+			}
+		})
+	}
+}
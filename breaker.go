@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrDBUnavailable = errors.New("db: unavailable, circuit breaker is open")
+
+// breakerDB is a circuit breaker around a DB. [1]
+//   - closed    : stores go through, consecutive failures are counted.
+//   - open      : after threshold failures in a row, stores fail fast with ErrDBUnavailable for cooldown.
+//   - half-open : after the cooldown one trial store goes through, success closes the breaker, failure opens it again.
+type breakerDB struct {
+	db        DB
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time // zero while closed
+	trial     bool      // a half-open trial store is in flight
+}
+
+func WithBreakerDB(db DB, threshold int, cooldown time.Duration) DB {
+	return &breakerDB{db: db, threshold: max(threshold, 1), cooldown: cooldown}
+}
+
+func (b *breakerDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	if !b.allow() {
+		return "", ErrDBUnavailable
+	}
+	id, err := b.db.StoreToDB(ctx, value)
+	b.record(err)
+	return id, err
+}
+
+func (b *breakerDB) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false // open, or half-open with the one trial already underway
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breakerDB) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	switch {
+	case err == nil:
+		b.failures, b.openUntil = 0, time.Time{}
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// the caller gave up, that says nothing about the DB's health
+	default:
+		b.failures++
+		if b.failures >= b.threshold || !b.openUntil.IsZero() { // a failed half-open trial reopens right away
+			b.openUntil = time.Now().Add(b.cooldown)
+		}
+	}
+}
+
+/*
+[1] : When the DB is down every caller waiting out its timeout piles up goroutines and connections, and the retries
+			keep hammering a DB that's trying to come back. Failing fast for a while gives both sides room to breathe.
+*/
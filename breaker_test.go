@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithBreakerDB(t *testing.T) {
+	const cooldown = 30 * time.Millisecond
+	errDown := errors.New("db is down")
+
+	type step struct {
+		wait    time.Duration // before the store
+		err     error         // what the inner DB answers, if it's called
+		wantErr error
+	}
+	tests := []struct {
+		name      string
+		steps     []step
+		wantCalls int
+	}{
+		{"trips after threshold failures", []step{
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{0, nil, ErrDBUnavailable}, // fails fast, the DB isn't asked
+		}, 3},
+		{"a success resets the count", []step{
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{0, nil, nil},
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{0, nil, nil},
+		}, 6},
+		{"recovers after the cooldown", []step{
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{cooldown + 10*time.Millisecond, nil, nil}, // the half-open trial succeeds
+			{0, nil, nil},
+		}, 5},
+		{"a failed trial reopens at once", []step{
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{0, errDown, errDown},
+			{cooldown + 10*time.Millisecond, errDown, errDown},
+			{0, nil, ErrDBUnavailable},
+		}, 4},
+		{"cancellations don't count", []step{
+			{0, context.Canceled, context.Canceled},
+			{0, context.DeadlineExceeded, context.DeadlineExceeded},
+			{0, context.Canceled, context.Canceled},
+			{0, nil, nil},
+		}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyDB{}
+			db := WithBreakerDB(inner, 3, cooldown)
+			for i, s := range tt.steps {
+				time.Sleep(s.wait)
+				inner.errs = nil
+				if s.err != nil {
+					inner.errs = []error{s.err}
+				}
+				if _, err := db.StoreToDB(context.Background(), "v"); !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: err = %v, want %v", i, err, s.wantErr)
+				}
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("%d calls reached the DB, want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// While half-open only one trial store goes through, the others keep failing fast.
+func TestWithBreakerDBSingleTrial(t *testing.T) {
+	release := make(chan struct{})
+	inner := &blockingDB{release: release}
+	b := &breakerDB{db: inner, threshold: 1, cooldown: time.Millisecond, openUntil: time.Now().Add(-time.Second)}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.StoreToDB(context.Background(), "trial")
+		done <- err
+	}()
+	for inner.running.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := b.StoreToDB(context.Background(), "v"); !errors.Is(err, ErrDBUnavailable) {
+		t.Errorf("second store during the trial = %v, want ErrDBUnavailable", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.StoreToDB(context.Background(), "v"); err != nil {
+		t.Errorf("after a successful trial = %v, want the breaker closed", err)
+	}
+}
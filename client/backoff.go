@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff decides how long to wait before retry number attempt (starting at 0).
+type Backoff interface {
+	Delay(attempt int) time.Duration
+}
+
+// BackoffFunc lets a plain function be used as a Backoff, the same trick as http.HandlerFunc.
+type BackoffFunc func(attempt int) time.Duration
+
+func (f BackoffFunc) Delay(attempt int) time.Duration { return f(attempt) }
+
+// ConstantBackoff always waits d.
+func ConstantBackoff(d time.Duration) BackoffFunc {
+	return func(int) time.Duration { return d }
+}
+
+// ExponentialBackoff waits base, 2*base, 4*base... never more than max.
+func ExponentialBackoff(base, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		if attempt < 0 {
+			attempt = 0
+		}
+		d := base
+		for range attempt {
+			d *= 2
+			if d >= max || d <= 0 { // d <= 0 means we overflowed
+				return max
+			}
+		}
+		return min(d, max)
+	}
+}
+
+// JitteredBackoff picks a random delay between 0 and whatever b would wait ("full jitter"). [1]
+func JitteredBackoff(b Backoff) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := b.Delay(attempt)
+		if d <= 0 {
+			return 0
+		}
+		return rand.N(d + 1)
+	}
+}
+
+/*
+[1] : If many clients fail at the same moment (an upstream restart), plain exponential backoff
+			makes them all retry at the same moments too. Spreading every delay randomly over [0, d]
+			breaks up those waves of synchronized retries.
+*/
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(100*time.Millisecond, time.Second)
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{-1, 100 * time.Millisecond},
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second}, // 1.6s capped
+		{10, time.Second},
+		{100, time.Second}, // would overflow without the cap
+	}
+	for _, tt := range tests {
+		if got := b.Delay(tt.attempt); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff(50 * time.Millisecond)
+	for attempt := range 5 {
+		if got := b.Delay(attempt); got != 50*time.Millisecond {
+			t.Errorf("Delay(%d) = %v, want 50ms", attempt, got)
+		}
+	}
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	tests := []struct {
+		name  string
+		inner Backoff
+	}{
+		{"constant", ConstantBackoff(10 * time.Millisecond)},
+		{"exponential", ExponentialBackoff(time.Millisecond, 50*time.Millisecond)},
+		{"zero", ConstantBackoff(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := JitteredBackoff(tt.inner)
+			for attempt := range 10 {
+				limit := tt.inner.Delay(attempt)
+				for range 100 {
+					if d := b.Delay(attempt); d < 0 || d > limit {
+						t.Fatalf("Delay(%d) = %v, want within [0, %v]", attempt, d, limit)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportUsesBackoff(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var asked []int
+	c := &http.Client{Transport: &RetryTransport{
+		Attempts: 5,
+		Backoff:  BackoffFunc(func(attempt int) time.Duration { asked = append(asked, attempt); return 0 }),
+		Logger:   logger.Nop,
+	}}
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || hits.Load() != 3 {
+		t.Errorf("got %d after %d attempts, want 200 after 3", res.StatusCode, hits.Load())
+	}
+	if len(asked) != 2 || asked[0] != 0 || asked[1] != 1 {
+		t.Errorf("Backoff asked for attempts %v, want [0 1]", asked)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+A private in-memory HTTP cache, as a RoundTripper.
+
+Servers describe how long a response may be reused with Cache-Control:
+	Cache-Control: max-age=60   fresh for 60 seconds, no need to ask again until then.
+	Cache-Control: no-store     never keep a copy.
+Once a response goes stale it isn't necessarily useless: if it came with an ETag (a version identifier),
+we can ask the server "still this version?" with If-None-Match, and a 304 Not Modified means we can keep using our copy,
+without the server resending the body.
+
+Only GET responses with a 200 are cached, keyed by URL. Vary and the request's own Cache-Control are ignored,
+this is a teaching cache, not a full RFC 9111 implementation.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	raw     []byte // the whole response as it came off the wire, status line and headers included [1]
+	etag    string
+	expires time.Time // guarded by CacheTransport.mu, raw and etag never change
+}
+
+type CacheTransport struct {
+	transport http.RoundTripper
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func NewCacheTransport(next http.RoundTripper) *CacheTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &CacheTransport{transport: next, entries: make(map[string]*cacheEntry)}
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.transport.RoundTrip(req)
+	}
+	key := req.URL.String()
+
+	t.mu.Lock()
+	entry := t.entries[key]
+	fresh := entry != nil && time.Now().Before(entry.expires) // expires changes on a 304, read it under the lock
+	t.mu.Unlock()
+
+	if fresh {
+		return entry.response(req)
+	}
+
+	if entry != nil && entry.etag != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified && entry != nil {
+		res.Body.Close()
+		t.mu.Lock()
+		entry.expires = time.Now().Add(freshness(res.Header)) // the 304 carries the new max-age
+		t.mu.Unlock()
+		return entry.response(req)
+	}
+
+	return t.store(key, res)
+}
+
+// store keeps a copy of res if it's cacheable and returns a response the caller can still read.
+func (t *CacheTransport) store(key string, res *http.Response) (*http.Response, error) {
+	cc := res.Header.Get("Cache-Control")
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || hasDirective(cc, "no-store") || (maxAge(cc) < 0 && etag == "") {
+		return res, nil
+	}
+
+	raw, err := httputil.DumpResponse(res, true) // reads the body and puts a fresh copy back on res
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.entries[key] = &cacheEntry{raw: raw, etag: etag, expires: time.Now().Add(freshness(res.Header))}
+	t.mu.Unlock()
+	return res, nil
+}
+
+func (e *cacheEntry) response(req *http.Request) (*http.Response, error) {
+	res, err := responseFromRaw(e.raw, req)
+	if err != nil {
+		return nil, err
+	}
+	res.Header.Set("X-From-Cache", "1")
+	return res, nil
+}
+
+// freshness is the max-age of a response, 0 (stale right away, revalidate every time) if it has none.
+func freshness(h http.Header) time.Duration {
+	age := maxAge(h.Get("Cache-Control"))
+	if age < 0 || hasDirective(h.Get("Cache-Control"), "no-cache") {
+		return 0
+	}
+	return time.Duration(age) * time.Second
+}
+
+// maxAge returns the max-age directive in seconds, or -1 if there's none.
+func maxAge(cc string) int {
+	for _, d := range strings.Split(cc, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(d), "=")
+		if ok && strings.EqualFold(name, "max-age") {
+			if n, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && n >= 0 {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+func hasDirective(cc, directive string) bool {
+	for _, d := range strings.Split(cc, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+[1] : Storing the raw bytes and parsing them back with http.ReadResponse gives every caller
+			its own *http.Response with its own Body, instead of several callers sharing (and draining) one reader.
+*/
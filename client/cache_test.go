@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		}
+		w.Write([]byte("body of " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path      string
+		wantHits  int32 // server hits for 3 requests
+		fromCache bool  // whether the last one says X-From-Cache
+	}{
+		{"/fresh", 1, true},
+		{"/etag", 3, true}, // revalidated every time, but the 304s don't resend the body
+		{"/nostore", 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			hits.Store(0)
+			client := &http.Client{Transport: NewCacheTransport(nil)}
+			var res *http.Response
+			for range 3 {
+				var err error
+				res, err = client.Get(srv.URL + tt.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(res.Body)
+				res.Body.Close()
+				if string(body) != "body of "+tt.path {
+					t.Fatalf("body = %q", body)
+				}
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("server hit %d times, want %d", hits.Load(), tt.wantHits)
+			}
+			if got := res.Header.Get("X-From-Cache") == "1"; got != tt.fromCache {
+				t.Errorf("from cache = %v, want %v", got, tt.fromCache)
+			}
+		})
+	}
+}
+
+// roundTripFunc lets a plain function be a RoundTripper, the same trick as http.HandlerFunc.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Run with -race: concurrent revalidations write an entry's expiry while other requests check it.
+// The upstream is a plain function, a real connection pool would order the goroutines and hide the race.
+func TestCacheTransportConcurrentRevalidation(t *testing.T) {
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {`"v1"`}}, Request: req}
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			res.StatusCode = http.StatusNotModified
+			time.Sleep(time.Millisecond) // keep the revalidations overlapping
+		}
+		res.Body = io.NopCloser(strings.NewReader("hello"))
+		return res, nil
+	})
+	cache := NewCacheTransport(upstream)
+	get := func() {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RequestURI = ""
+		res, err := cache.RoundTrip(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+	get() // cache the entry, every request after this revalidates it
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			get()
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
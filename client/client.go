@@ -6,11 +6,23 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
 )
 
 func main() {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
+		Transport: &HeaderTransport{
+			UserAgent: "go-backend-client/1.0",
+			Headers:   http.Header{"X-My-Client": {"LearninGo"}}, // sent on every request
+			Transport: &RetryTransport{
+				Attempts: 3,
+				Backoff:  JitteredBackoff(ExponentialBackoff(100*time.Millisecond, 2*time.Second)),
+				Logger:   logger.Default(),
+			},
+		},
+		CheckRedirect: RedirectPolicy{MaxRedirects: 5, StripAuthOnCrossHost: true}.CheckRedirect,
 	}
 
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://jsonplaceholder.typicode.com/todos/1", nil)
@@ -18,8 +30,6 @@ func main() {
 		panic(err)
 	}
 
-	req.Header.Add("X-My-Client", "LearninGo")
-
 	res, err := client.Do(req)
 	if err != nil {
 		panic(err)
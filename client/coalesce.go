@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// CoalescingTransport merges concurrent identical GETs (same URL) into one upstream request, "single flight":
+// the first caller does the request, the callers arriving while it's in flight wait and share its response.
+// Every caller still gets its own *http.Response with its own readable Body.
+//
+// Requests carrying credentials (Authorization, Cookie) are never merged, one user must not get another's response,
+// nor are Range requests. Only requests that also agree on the negotiation headers (see varyHeaders) share a flight. [1]
+// The shared request runs with the first caller's context, if that one gets canceled its waiters fail too.
+type CoalescingTransport struct {
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	inflight map[string]*flight
+}
+
+type flight struct {
+	done chan struct{} // closed once raw/err are set
+	raw  []byte
+	err  error
+}
+
+func NewCoalescingTransport(next http.RoundTripper) *CoalescingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &CoalescingTransport{transport: next, inflight: make(map[string]*flight)}
+}
+
+func (t *CoalescingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != "" || req.Header.Get("Range") != "" {
+		return t.transport.RoundTrip(req)
+	}
+	key := coalesceKey(req)
+
+	t.mu.Lock()
+	f, ok := t.inflight[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		t.inflight[key] = f
+	}
+	t.mu.Unlock()
+
+	if ok {
+		select {
+		case <-f.done:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		if f.err != nil {
+			return nil, f.err
+		}
+		return responseFromRaw(f.raw, req)
+	}
+
+	f.raw, f.err = t.do(req)
+
+	t.mu.Lock()
+	delete(t.inflight, key) // later requests start a new flight, this isn't a cache
+	t.mu.Unlock()
+	close(f.done)
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	return responseFromRaw(f.raw, req)
+}
+
+// varyHeaders are the request headers a server commonly picks the representation by.
+var varyHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language"}
+
+// coalesceKey is the URL plus the values of varyHeaders, two requests differing in any of them get their own flight.
+func coalesceKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.URL.String())
+	for _, h := range varyHeaders {
+		b.WriteString("\n")
+		b.WriteString(strings.Join(req.Header.Values(h), ", "))
+	}
+	return b.String()
+}
+
+// do performs the shared request and returns the whole response as bytes, so it can be handed out many times.
+func (t *CoalescingTransport) do(req *http.Request) ([]byte, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return httputil.DumpResponse(res, true)
+}
+
+// responseFromRaw parses a response dumped with httputil.DumpResponse back into a fresh *http.Response.
+func responseFromRaw(raw []byte, req *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+}
+
+/*
+[1] : The same URL isn't always the same response: a client asking for JSON and one asking for XML,
+			or gzip and no gzip, would each get whatever the first request of the flight asked for.
+			Range asks for part of the body, the rest of the callers want all of it (and a 206 they didn't ask for
+			is worse), those go upstream on their own. The response's Vary header would say exactly which headers matter,
+			but it's only known once the first response is back, too late to decide who waits for it.
+*/
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescingTransport(t *testing.T) {
+	type call struct {
+		method string
+		header http.Header
+	}
+	get := func(kv ...string) call {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return call{"GET", h}
+	}
+
+	tests := []struct {
+		name         string
+		calls        []call
+		wantUpstream int32
+	}{
+		{"identical GETs merged", []call{get(), get(), get()}, 1},
+		{"same Accept merged", []call{get("Accept", "application/json"), get("Accept", "application/json")}, 1},
+		{"different Accept", []call{get("Accept", "application/json"), get("Accept", "text/xml"), get()}, 3},
+		{"different Accept-Encoding", []call{get("Accept-Encoding", "gzip"), get()}, 2},
+		{"Range never merged", []call{get("Range", "bytes=0-9"), get("Range", "bytes=0-9")}, 2},
+		{"Authorization never merged", []call{get("Authorization", "Bearer a"), get("Authorization", "Bearer a")}, 2},
+		{"POST never merged", []call{{"POST", http.Header{}}, {"POST", http.Header{}}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upstream atomic.Int32
+			transport := NewCoalescingTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				upstream.Add(1)
+				time.Sleep(50 * time.Millisecond) // long enough for every caller to join the flight
+				body := "accept=" + req.Header.Get("Accept")
+				return &http.Response{
+					StatusCode: 200, ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{},
+					ContentLength: int64(len(body)), Body: io.NopCloser(strings.NewReader(body)),
+				}, nil
+			}))
+
+			var wg sync.WaitGroup
+			for _, c := range tt.calls {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, _ := http.NewRequest(c.method, "http://api.test/items", nil)
+					req.Header = c.header
+					res, err := transport.RoundTrip(req)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer res.Body.Close()
+					b, _ := io.ReadAll(res.Body)
+					if want := "accept=" + c.header.Get("Accept"); string(b) != want {
+						t.Errorf("got %q, want %q", b, want)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := upstream.Load(); got != tt.wantUpstream {
+				t.Errorf("%d upstream requests, want %d", got, tt.wantUpstream)
+			}
+		})
+	}
+}
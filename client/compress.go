@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+)
+
+// NewCompressedRequest builds a request whose body is gzipped when it's larger than threshold bytes.
+// The compressed body is fully buffered, so Content-Length is the compressed size
+// and the request can be replayed (GetBody) on redirects and retries. [1]
+func NewCompressedRequest(ctx context.Context, method, url string, body []byte, threshold int) (*http.Request, error) {
+	if len(body) <= threshold {
+		return http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil { // Close flushes the last block and writes the gzip footer
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	return req, nil
+}
+
+// NewStreamingCompressedRequest gzips body on the fly while it's being sent.
+// The size isn't known upfront, so the request goes out with chunked Transfer-Encoding. [2]
+func NewStreamingCompressedRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, body)
+		if err == nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err) // a nil error closes the pipe normally
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	return req, nil
+}
+
+/*
+[1] : http.NewRequest recognises *bytes.Reader, *bytes.Buffer and *strings.Reader bodies
+			and fills in ContentLength and GetBody for us.
+
+[2] : For any other io.Reader, ContentLength is left at 0 with a non-nil body, which the transport treats as unknown
+			and sends as "Transfer-Encoding: chunked". Note the server must support compressed request bodies,
+			unlike responses there's no negotiation for these.
+*/
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// received is what the server side saw of one request, after undoing the gzip.
+type received struct {
+	encoding      string
+	contentLength int64
+	chunked       bool
+	body          []byte
+}
+
+func decompressingServer(t *testing.T, got chan<- received) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := received{
+			encoding:      r.Header.Get("Content-Encoding"),
+			contentLength: r.ContentLength,
+			chunked:       len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked",
+		}
+		var body io.Reader = r.Body
+		if rec.encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("server: %v", err)
+				return
+			}
+			body = gz
+		}
+		rec.body, _ = io.ReadAll(body)
+		got <- rec
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewCompressedRequest(t *testing.T) {
+	large := bytes.Repeat([]byte("compress me please "), 1000)
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+	}{
+		{"below threshold", []byte("small"), ""},
+		{"above threshold", large, "gzip"},
+	}
+
+	got := make(chan received, 1)
+	srv := decompressingServer(t, got)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewCompressedRequest(context.Background(), "POST", srv.URL, tt.body, 1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			rec := <-got
+			if rec.encoding != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", rec.encoding, tt.encoding)
+			}
+			if rec.contentLength != req.ContentLength || rec.contentLength <= 0 {
+				t.Errorf("server saw Content-Length %d, request had %d", rec.contentLength, req.ContentLength)
+			}
+			if tt.encoding == "gzip" && rec.contentLength >= int64(len(tt.body)) {
+				t.Errorf("compressed body is %d bytes, original %d", rec.contentLength, len(tt.body))
+			}
+			if !bytes.Equal(rec.body, tt.body) {
+				t.Errorf("server got %d bytes that don't match the %d sent", len(rec.body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestNewStreamingCompressedRequest(t *testing.T) {
+	got := make(chan received, 1)
+	srv := decompressingServer(t, got)
+
+	body := bytes.Repeat([]byte("streamed "), 10000)
+	req, err := NewStreamingCompressedRequest(context.Background(), "POST", srv.URL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	rec := <-got
+	if rec.encoding != "gzip" || !rec.chunked {
+		t.Errorf("got Content-Encoding %q, chunked %v, want gzip and chunked", rec.encoding, rec.chunked)
+	}
+	if !bytes.Equal(rec.body, body) {
+		t.Errorf("server got %d bytes that don't match the %d sent", len(rec.body), len(body))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrConflict means the resource changed since we read it: the server refused an If-Match update with a 412.
+var ErrConflict = errors.New("client: resource was modified concurrently")
+
+// UpdateIfMatch sends a JSON PUT or PATCH that only applies if the resource still has etag
+// (optimistic concurrency, no locks held between reading and writing). [1]
+// On success it returns the resource's new ETag. On ErrConflict, fetch the resource again, redo the change and retry:
+//
+//	for {
+//		current, etag := get()
+//		newETag, err := UpdateIfMatch(ctx, client, http.MethodPut, url, etag, modify(current))
+//		if !errors.Is(err, ErrConflict) { return newETag, err }
+//	}
+func UpdateIfMatch(ctx context.Context, client *http.Client, method, url, etag string, body []byte) (newETag string, err error) {
+	if method != http.MethodPut && method != http.MethodPatch {
+		return "", fmt.Errorf("client: conditional update needs PUT or PATCH, got %s", method)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", etag) // the ETag as the server sent it, quotes included
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body) // drain it, so the connection can be reused
+
+	switch {
+	case res.StatusCode == http.StatusPreconditionFailed:
+		return "", ErrConflict
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return res.Header.Get("ETag"), nil
+	default:
+		return "", fmt.Errorf("unexpected status: got %v", res.Status)
+	}
+}
+
+/*
+[1] : Without If-Match two clients that read the same version would both succeed, and the second write
+			silently undoes the first (a "lost update"). With it the server compares ETags and only one of them wins.
+*/
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// versionedServer keeps one resource and only updates it when If-Match names its current version.
+func versionedServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	version := 1
+	etag := func() string { return fmt.Sprintf(`"v%d"`, version) }
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		if body, _ := io.ReadAll(r.Body); string(body) != `{"title":"new"}` {
+			t.Errorf("body = %q", body)
+		}
+		switch r.Header.Get("If-Match") {
+		case etag():
+			version++
+			w.Header().Set("ETag", etag())
+		case "teapot":
+			w.WriteHeader(http.StatusTeapot)
+		default:
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		etag     string
+		wantETag string
+		wantErr  error // ErrConflict, or errAny for some other error
+	}{
+		{"fresh etag", http.MethodPut, `"v1"`, `"v2"`, nil},
+		{"patch", http.MethodPatch, `"v1"`, `"v2"`, nil},
+		{"stale etag", http.MethodPut, `"v0"`, "", ErrConflict},
+		{"other status", http.MethodPut, "teapot", "", errAny},
+		{"not an update", http.MethodPost, `"v1"`, "", errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := versionedServer(t)
+			etag, err := UpdateIfMatch(context.Background(), srv.Client(), tt.method, srv.URL, tt.etag, []byte(`{"title":"new"}`))
+
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil || errors.Is(err, ErrConflict) {
+					t.Errorf("err = %v, want an error other than ErrConflict", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if etag != tt.wantETag {
+				t.Errorf("etag = %q, want %q", etag, tt.wantETag)
+			}
+		})
+	}
+}
+
+// The retry loop from the doc comment: the first attempt loses the race, the second one goes through.
+func TestUpdateIfMatchRetry(t *testing.T) {
+	srv := versionedServer(t)
+	ctx := context.Background()
+	if _, err := UpdateIfMatch(ctx, srv.Client(), http.MethodPut, srv.URL, `"v1"`, []byte(`{"title":"new"}`)); err != nil {
+		t.Fatal(err) // someone else's update, the resource is at v2 now
+	}
+
+	_, err := UpdateIfMatch(ctx, srv.Client(), http.MethodPut, srv.URL, `"v1"`, []byte(`{"title":"new"}`))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+	etag, err := UpdateIfMatch(ctx, srv.Client(), http.MethodPut, srv.URL, `"v2"`, []byte(`{"title":"new"}`))
+	if err != nil || etag != `"v3"` {
+		t.Errorf("retry = %q, %v, want \"v3\"", etag, err)
+	}
+}
+
+var errAny = errors.New("any error")
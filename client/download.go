@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Download streams url into destPath without holding the body in memory.
+// The body goes to a temp file next to destPath which is renamed into place only once complete,
+// so destPath is never left half written. [1]
+// onProgress (may be nil) gets the bytes downloaded so far and the total, -1 when the server didn't send Content-Length.
+func Download(ctx context.Context, client *http.Client, url, destPath string, onProgress func(downloaded, total int64)) (err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: got %v", res.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	var dst io.Writer = tmp
+	if onProgress != nil {
+		dst = &progressWriter{w: tmp, total: res.ContentLength, onProgress: onProgress}
+	}
+
+	if _, err = io.Copy(dst, res.Body); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil { // make sure the bytes are on disk before the rename makes them visible
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), destPath)
+}
+
+type progressWriter struct {
+	w          io.Writer
+	downloaded int64
+	total      int64
+	onProgress func(downloaded, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.downloaded += int64(n)
+	p.onProgress(p.downloaded, p.total)
+	return n, err
+}
+
+/*
+[1] : A rename within the same directory (hence the temp file next to destPath and not in os.TempDir(),
+			which may be another filesystem) is atomic: readers see either the old file or the complete new one.
+*/
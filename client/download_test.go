@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 100_000) // 1MB, several writes worth
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file":
+			w.Header().Set("Content-Length", "1000000")
+			w.Write(payload)
+		case "/chunked":
+			w.Write(payload[:500_000])
+			w.(http.Flusher).Flush() // no Content-Length from here on
+			w.Write(payload[500_000:])
+		case "/truncated":
+			w.Header().Set("Content-Length", "1000000")
+			w.Write(payload[:1000]) // and hang up
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path      string
+		wantErr   bool
+		wantTotal int64
+	}{
+		{"/file", false, 1_000_000},
+		{"/chunked", false, -1},
+		{"/truncated", true, 1_000_000},
+		{"/missing", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "out.bin")
+
+			var calls int
+			var last, total int64
+			err := Download(context.Background(), srv.Client(), srv.URL+tt.path, dest, func(downloaded, n int64) {
+				calls++
+				last, total = downloaded, n
+			})
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Download succeeded")
+				}
+				entries, _ := os.ReadDir(dir)
+				if len(entries) != 0 {
+					t.Errorf("left %d files behind, e.g. %s", len(entries), entries[0].Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Errorf("file has %d bytes that don't match the %d served", len(got), len(payload))
+			}
+			if calls < 2 || last != int64(len(payload)) || total != tt.wantTotal {
+				t.Errorf("progress called %d times, last with (%d, %d), want several ending with (%d, %d)",
+					calls, last, total, len(payload), tt.wantTotal)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("%d files in the directory, want only %s", len(entries), dest)
+			}
+		})
+	}
+}
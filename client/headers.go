@@ -0,0 +1,34 @@
+package main
+
+import "net/http"
+
+// HeaderTransport brands every outgoing request with a User-Agent and a set of default headers.
+// Headers the request already has are left alone, so a caller can still override any of them per request.
+type HeaderTransport struct {
+	UserAgent string
+	Headers   http.Header
+	Transport http.RoundTripper
+}
+
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context()) // RoundTrippers must not modify the caller's request
+	if _, ok := req.Header["User-Agent"]; t.UserAgent != "" && !ok {
+		req.Header.Set("User-Agent", t.UserAgent) // [1]
+	}
+	for name, values := range t.Headers {
+		if _, ok := req.Header[http.CanonicalHeaderKey(name)]; !ok {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return next.RoundTrip(req)
+}
+
+/*
+[1] : Without one, Go sends "User-Agent: Go-http-client/1.1". Setting it to an empty string sends none at all,
+			that's a deliberate choice by the caller too, so we check whether the key exists rather than Get() == "".
+*/
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaderTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.Header }))
+	defer srv.Close()
+
+	transport := &HeaderTransport{
+		UserAgent: "go-backend-client/1.0",
+		Headers:   http.Header{"X-My-Client": {"go-backend"}, "accept": {"application/json"}},
+	}
+	tests := []struct {
+		name     string
+		set      http.Header // on the request itself
+		want     map[string]string
+		wantNoUA bool
+	}{
+		{"defaults", nil, map[string]string{
+			"User-Agent": "go-backend-client/1.0", "X-My-Client": "go-backend", "Accept": "application/json",
+		}, false},
+		{"request headers win", http.Header{"User-Agent": {"curl/8"}, "Accept": {"text/html"}}, map[string]string{
+			"User-Agent": "curl/8", "X-My-Client": "go-backend", "Accept": "text/html",
+		}, false},
+		{"empty user agent sends none", http.Header{"User-Agent": {""}}, map[string]string{
+			"X-My-Client": "go-backend",
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", srv.URL, nil)
+			for k, v := range tt.set {
+				req.Header[k] = v
+			}
+			before := req.Header.Clone()
+			res, err := (&http.Client{Transport: transport}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			for k, v := range tt.want {
+				if got.Get(k) != v {
+					t.Errorf("%s = %q, want %q", k, got.Get(k), v)
+				}
+			}
+			if _, ok := got["User-Agent"]; ok == tt.wantNoUA {
+				t.Errorf("User-Agent sent: %v, want %v", ok, !tt.wantNoUA)
+			}
+			if len(req.Header) != len(before) {
+				t.Errorf("the caller's request was modified: %v", req.Header)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// HostLimitTransport allows at most limit in-flight requests per host (host:port).
+// Excess requests wait for a free slot, or give up when their context is canceled.
+// A request stays in flight until its response body is closed, not just until the headers arrive.
+// A limit <= 0 means no limit, requests go straight to the next transport. [2]
+type HostLimitTransport struct {
+	limit     int
+	transport http.RoundTripper
+
+	mu   sync.Mutex
+	sems map[string]chan struct{} // one buffered channel per host, used as a counting semaphore [1]
+}
+
+func NewHostLimitTransport(limit int, next http.RoundTripper) *HostLimitTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &HostLimitTransport{limit: limit, transport: next, sems: make(map[string]chan struct{})}
+}
+
+func (t *HostLimitTransport) semaphore(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sem, ok := t.sems[host]
+	if !ok {
+		sem = make(chan struct{}, t.limit)
+		t.sems[host] = sem
+	}
+	return sem
+}
+
+func (t *HostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.limit <= 0 {
+		return t.transport.RoundTrip(req)
+	}
+	sem := t.semaphore(req.URL.Host)
+
+	select {
+	case sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		<-sem
+		return nil, err
+	}
+
+	res.Body = &releasingBody{ReadCloser: res.Body, release: func() { <-sem }}
+	return res, nil
+}
+
+// releasingBody frees the semaphore slot the first time the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+/*
+[1] : Sending into a channel with a buffer of size n only blocks once n values are in it,
+			so "send to acquire, receive to release" lets at most n holders in at a time.
+
+[2] : make(chan struct{}, 0) is an unbuffered channel, a send on it waits for a receiver that never comes,
+			so a zero limit would hang every request instead of letting none through or all of them.
+*/
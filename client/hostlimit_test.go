@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimitTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		hosts    []string
+		wantPeak int32 // most requests in flight to a.test at once
+	}{
+		{"limit 2", 2, []string{"a.test", "a.test", "a.test", "a.test"}, 2},
+		{"per host", 1, []string{"a.test", "b.test", "a.test", "b.test"}, 1},
+		{"limit 0 is unlimited", 0, []string{"a.test", "a.test", "a.test", "a.test"}, 4},
+		{"negative is unlimited", -1, []string{"a.test", "a.test", "a.test"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak atomic.Int32
+			upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Host == "a.test" {
+					n := running.Add(1)
+					for old := peak.Load(); n > old && !peak.CompareAndSwap(old, n); old = peak.Load() {
+					}
+					time.Sleep(20 * time.Millisecond)
+					running.Add(-1)
+				}
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+			})
+			client := &http.Client{Transport: NewHostLimitTransport(tt.limit, upstream)}
+
+			var wg sync.WaitGroup
+			for _, host := range tt.hosts {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+					defer cancel()
+					req, _ := http.NewRequestWithContext(ctx, "GET", "http://"+host+"/", nil)
+					res, err := client.Do(req)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					res.Body.Close() // frees the slot
+				}()
+			}
+			wg.Wait()
+
+			if got := peak.Load(); got != tt.wantPeak {
+				t.Errorf("%d requests in flight at once, want %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+// A slot is only freed once the body is closed, a waiting request gives up with its context.
+func TestHostLimitTransportHeldUntilBodyClosed(t *testing.T) {
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	})
+	client := &http.Client{Transport: NewHostLimitTransport(1, upstream)}
+
+	first, err := client.Get("http://a.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://a.test/", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("second request got through while the first body was open")
+	}
+
+	first.Body.Close()
+	first.Body.Close() // a second close mustn't free a second slot
+	if res, err := client.Get("http://a.test/"); err != nil {
+		t.Fatal(err)
+	} else {
+		res.Body.Close()
+	}
+}
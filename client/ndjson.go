@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const maxNDJSONLine = 1 << 20 // 1mb, a longer record fails the stream
+
+// StreamNDJSON GETs url and hands every line of the newline delimited JSON body to fn, decoded into a T,
+// as it arrives, so the body is never held in memory as a whole. [1]
+// It stops at the first error fn returns (and returns it), on a line that isn't valid JSON, or when ctx is done.
+func StreamNDJSON[T any](ctx context.Context, client *http.Client, url string, fn func(T) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // canceling ctx also unblocks a read of the body, which is how we stop mid stream
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: got %v", res.Status)
+	}
+
+	sc := bufio.NewScanner(res.Body)
+	sc.Buffer(make([]byte, 0, 4096), maxNDJSONLine)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue // tolerate blank lines, a trailing one especially
+		}
+		if err := ctx.Err(); err != nil { // lines already buffered would otherwise still reach fn
+			return err
+		}
+		var v T
+		if err := json.Unmarshal(sc.Bytes(), &v); err != nil {
+			return fmt.Errorf("ndjson line %d: %w", line, err)
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err() // report the cancellation rather than the read error it caused
+		}
+		return err
+	}
+	return nil
+}
+
+/*
+[1] : NDJSON is one complete JSON value per line: {"id":1}\n{"id":2}\n...
+			Unlike one big JSON array it can be produced and consumed record by record,
+			which is why it's popular for exports, logs and long running queries.
+*/
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type record struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	errStop := errors.New("seen enough")
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		stopAt  int // fn fails on this id, 0 never
+		want    []record
+		wantErr error // errAny for some error
+	}{
+		{"every record", 200, "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n{\"id\":3,\"name\":\"c\"}\n", 0,
+			[]record{{1, "a"}, {2, "b"}, {3, "c"}}, nil},
+		{"blank lines and no final newline", 200, "{\"id\":1}\n\n  \n{\"id\":2}", 0,
+			[]record{{ID: 1}, {ID: 2}}, nil},
+		{"fn stops it", 200, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n", 2,
+			[]record{{ID: 1}, {ID: 2}}, errStop},
+		{"bad line", 200, "{\"id\":1}\nnot json\n{\"id\":3}\n", 0,
+			[]record{{ID: 1}}, errAny},
+		{"line too long", 200, "{\"name\":\"" + strings.Repeat("a", maxNDJSONLine) + "\"}\n", 0,
+			nil, errAny},
+		{"error status", 500, "{\"id\":1}\n", 0, nil, errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accept") != "application/x-ndjson" {
+					t.Errorf("Accept = %q", r.Header.Get("Accept"))
+				}
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			var got []record
+			err := StreamNDJSON(context.Background(), srv.Client(), srv.URL, func(r record) error {
+				got = append(got, r)
+				if r.ID == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil {
+					t.Error("err = nil, want an error")
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Records reach fn as they are sent, and canceling ctx ends a stream the server is still holding open.
+func TestStreamNDJSONCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= 2; i++ {
+			fmt.Fprintf(w, "{\"id\":%d}\n", i)
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done() // never sends the rest
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []int
+	done := make(chan error, 1)
+	go func() {
+		done <- StreamNDJSON(ctx, srv.Client(), srv.URL, func(r record) error {
+			got = append(got, r.ID)
+			if r.ID == 2 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamNDJSON still running after ctx was canceled")
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got ids %v, want 1 and 2", got)
+	}
+}
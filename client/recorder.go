@@ -0,0 +1,148 @@
+/*
+A VCR-style http.RoundTripper.
+
+An http.Client doesn't talk to the network itself, it hands every request to its Transport (an http.RoundTripper):
+	type RoundTripper interface {
+		RoundTrip(*http.Request) (*http.Response, error)
+	}
+So by swapping the Transport we can sit between the client and the network:
+- in Record mode, every request is sent for real and the request/response pair is saved to a "cassette" file.
+- in Replay mode, nothing goes out, the recorded response matching the method, URL and body is returned instead.
+This makes code using the client deterministic to test, even with the upstream down.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+type RecorderMode int
+
+const (
+	ModeRecord RecorderMode = iota
+	ModeReplay
+)
+
+type interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Body       []byte      `json:"body,omitempty"` // []byte is base64 encoded by encoding/json, so binary bodies survive
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Response   []byte      `json:"response,omitempty"`
+}
+
+type RecordingTransport struct {
+	mode      RecorderMode
+	path      string
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []interaction
+}
+
+// NewRecordingTransport records through next (http.DefaultTransport if nil) into the cassette at path,
+// or, in replay mode, loads the cassette so requests can be answered from it.
+func NewRecordingTransport(path string, mode RecorderMode, next http.RoundTripper) (*RecordingTransport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &RecordingTransport{mode: mode, path: path, transport: next}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &t.interactions); err != nil {
+			return nil, fmt.Errorf("recorder: reading cassette %s: %w", path, err)
+		}
+	}
+	return t, nil
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body)) // [1]
+	}
+
+	if t.mode == ModeReplay {
+		return t.replay(req, body)
+	}
+	return t.record(req, body)
+}
+
+func (t *RecordingTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, it := range t.interactions {
+		if it.Method == req.Method && it.URL == req.URL.String() && bytes.Equal(it.Body, body) {
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", it.StatusCode, http.StatusText(it.StatusCode)),
+				StatusCode:    it.StatusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        it.Header.Clone(),
+				Body:          io.NopCloser(bytes.NewReader(it.Response)),
+				ContentLength: int64(len(it.Response)),
+				Request:       req,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("recorder: no recorded response for %s %s", req.Method, req.URL)
+}
+
+func (t *RecordingTransport) record(req *http.Request, body []byte) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, interaction{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Body:       body,
+		StatusCode: res.StatusCode,
+		Header:     res.Header.Clone(),
+		Response:   resBody,
+	})
+	return res, t.save()
+}
+
+// save rewrites the whole cassette, it's tiny and this keeps the file valid JSON after every request.
+func (t *RecordingTransport) save() error {
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0o644)
+}
+
+/*
+[1] : We've consumed the body to remember it, so put a fresh reader back in place for the real transport.
+*/
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingTransport(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Seen", r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("echo:" + string(b)))
+	}))
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	type call struct{ method, path, body string }
+	calls := []call{{"GET", "/a", ""}, {"POST", "/b", "one"}, {"POST", "/b", "two"}}
+
+	do := func(c *http.Client, cl call) (int, string, string, error) {
+		req, _ := http.NewRequest(cl.method, upstream.URL+cl.path, strings.NewReader(cl.body))
+		res, err := c.Do(req)
+		if err != nil {
+			return 0, "", "", err
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return res.StatusCode, res.Header.Get("X-Seen"), string(b), nil
+	}
+
+	rec, err := NewRecordingTransport(cassette, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		status     int
+		seen, body string
+	}
+	var recorded []result
+	for _, cl := range calls {
+		status, seen, body, err := do(&http.Client{Transport: rec}, cl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded = append(recorded, result{status, seen, body})
+	}
+	upstream.Close() // replay must not need it
+
+	replay, err := NewRecordingTransport(cassette, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cl := range calls {
+		status, seen, body, err := do(&http.Client{Transport: replay}, cl)
+		if err != nil {
+			t.Fatalf("replaying %v: %v", cl, err)
+		}
+		if got := (result{status, seen, body}); got != recorded[i] {
+			t.Errorf("replayed %v, recorded %v", got, recorded[i])
+		}
+	}
+
+	if _, _, _, err := do(&http.Client{Transport: replay}, call{"POST", "/b", "three"}); err == nil {
+		t.Error("a body that was never recorded got a response")
+	}
+}
+
+func TestRecordingTransportMissingCassette(t *testing.T) {
+	if _, err := NewRecordingTransport(filepath.Join(t.TempDir(), "nope.json"), ModeReplay, nil); err == nil {
+		t.Error("replaying a missing cassette succeeded")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrTooManyRedirects = errors.New("client: too many redirects")
+	ErrRedirectBlocked  = errors.New("client: redirect to a host that isn't allowed")
+)
+
+// RedirectPolicy is plugged into http.Client.CheckRedirect, which the client calls before following each redirect.
+// Without one the client follows up to 10 redirects to anywhere.
+type RedirectPolicy struct {
+	MaxRedirects         int      // 0 means don't follow redirects at all
+	AllowedHosts         []string // hosts redirects may lead to, empty means any host
+	StripAuthOnCrossHost bool     // drop Authorization when a redirect leaves the original host [1]
+}
+
+// CheckRedirect gets the upcoming request and the requests made so far, oldest first.
+func (p RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > p.MaxRedirects {
+		return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, p.MaxRedirects)
+	}
+
+	if len(p.AllowedHosts) > 0 && !p.hostAllowed(req.URL.Hostname()) {
+		return fmt.Errorf("%w: %s", ErrRedirectBlocked, req.URL.Host)
+	}
+
+	if p.StripAuthOnCrossHost && !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+func (p RedirectPolicy) hostAllowed(host string) bool {
+	for _, allowed := range p.AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+[1] : A token meant for api.example.com must not be handed to wherever a redirect points,
+			an open redirect on the API would otherwise leak it to any host.
+			net/http already drops Authorization when the new host isn't the original domain or a subdomain of it,
+			this is stricter: any change of host (or port) strips the header.
+
+Returning an error from CheckRedirect makes client.Do return it (wrapped in a *url.Error) along with the last response.
+To stop following without an error, and get the redirect response itself, return http.ErrUseLastResponse.
+*/
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	// b reports the Authorization header it got, a redirects to wherever ?to= says, ?n= times.
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("auth=" + r.Header.Get("Authorization")))
+	}))
+	defer b.Close()
+	var a *httptest.Server
+	a = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		if n > 0 { // bounce on a itself first
+			http.Redirect(w, r, a.URL+"/?n="+strconv.Itoa(n-1)+"&to="+r.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+		switch r.URL.Query().Get("to") {
+		case "b":
+			http.Redirect(w, r, b.URL, http.StatusFound)
+		case "b-localhost":
+			http.Redirect(w, r, strings.Replace(b.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		default:
+			w.Write([]byte("auth=" + r.Header.Get("Authorization")))
+		}
+	}))
+	defer a.Close()
+
+	tests := []struct {
+		name     string
+		policy   RedirectPolicy
+		query    string
+		wantErr  error
+		wantBody string
+	}{
+		{"no redirect", RedirectPolicy{}, "", nil, "auth=Bearer secret"},
+		{"zero max redirects", RedirectPolicy{}, "?n=1", ErrTooManyRedirects, ""},
+		{"within max", RedirectPolicy{MaxRedirects: 3}, "?n=3", nil, "auth=Bearer secret"},
+		{"past max", RedirectPolicy{MaxRedirects: 3}, "?n=4", ErrTooManyRedirects, ""},
+		{"same host keeps auth", RedirectPolicy{MaxRedirects: 5, StripAuthOnCrossHost: true}, "?n=2", nil, "auth=Bearer secret"},
+		{"cross host keeps auth", RedirectPolicy{MaxRedirects: 5}, "?to=b", nil, "auth=Bearer secret"},
+		{"cross host strips auth", RedirectPolicy{MaxRedirects: 5, StripAuthOnCrossHost: true}, "?to=b", nil, "auth="},
+		{"allowed host", RedirectPolicy{MaxRedirects: 5, AllowedHosts: []string{"127.0.0.1"}}, "?to=b", nil, "auth=Bearer secret"},
+		{"blocked host", RedirectPolicy{MaxRedirects: 5, AllowedHosts: []string{"127.0.0.1"}}, "?to=b-localhost", ErrRedirectBlocked, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &http.Client{CheckRedirect: tt.policy.CheckRedirect}
+			req, _ := http.NewRequest("GET", a.URL+"/"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			res, err := c.Do(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
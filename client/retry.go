@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// DefaultRetryMethods are the idempotent methods, sending them twice has the same effect as sending them once. [2]
+var DefaultRetryMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete}
+
+// RetryTransport retries requests that failed with a network error, a 5xx or a 429,
+// waiting between attempts as decided by Backoff.
+type RetryTransport struct {
+	Attempts     int      // total attempts including the first one, values below 1 mean 1
+	Backoff      Backoff  // nil means retry immediately
+	RetryMethods []string // methods that may be retried, nil means DefaultRetryMethods
+	Transport    http.RoundTripper
+	Logger       logger.Logger // told about every retry, nil means logger.Default(), pass logger.Nop to silence it
+}
+
+func (t *RetryTransport) retryable(method string) bool {
+	methods := t.RetryMethods
+	if methods == nil {
+		methods = DefaultRetryMethods
+	}
+	return slices.Contains(methods, method)
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if t.Attempts <= 1 || !t.retryable(req.Method) {
+		return next.RoundTrip(req)
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		var err error
+		if req, err = bufferBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := next.RoundTrip(req)
+		if attempt+1 >= t.Attempts || !shouldRetry(res, err) {
+			return res, err
+		}
+		if res != nil {
+			res.Body.Close() // we're not handing this response out, free the connection
+		}
+
+		var delay time.Duration
+		if t.Backoff != nil {
+			delay = t.Backoff.Delay(attempt)
+		}
+		t.logRetry(req, attempt, delay, res, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context()) // [1]
+			req.Body = body
+		}
+	}
+}
+
+func (t *RetryTransport) logRetry(req *http.Request, attempt int, delay time.Duration, res *http.Response, err error) {
+	reason := "error"
+	if err == nil {
+		reason = res.Status
+	}
+	logger.Or(t.Logger).Info("retrying request", "method", req.Method, "url", req.URL.Redacted(),
+		"attempt", attempt+1, "reason", reason, "err", err, "delay", delay)
+}
+
+// bufferBody reads the body into memory so it can be sent again on every attempt.
+// Only done for retryable methods, a POST streaming a huge upload stays streamed.
+func bufferBody(req *http.Request) (*http.Request, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return req, nil
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+}
+
+/*
+[1] : A RoundTripper must not modify the request it's given, so the fresh body goes on a copy.
+
+[2] : A POST that timed out may well have been processed, retrying it could create the same thing twice.
+			Add http.MethodPost to RetryMethods only for endpoints that are safe to repeat (e.g. using an Idempotency-Key).
+*/
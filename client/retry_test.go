@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		retryMethods []string
+		streamed     bool // body is a plain io.Reader, without GetBody
+		wantBodies   []string
+		wantStatus   int
+	}{
+		{"post isn't retried", "POST", nil, false, []string{"payload"}, http.StatusServiceUnavailable},
+		{"put is retried", "PUT", nil, false, []string{"payload", "payload", "payload"}, http.StatusOK},
+		{"streamed put is buffered", "PUT", nil, true, []string{"payload", "payload", "payload"}, http.StatusOK},
+		{"get is retried", "GET", nil, false, []string{"", "", ""}, http.StatusOK},
+		{"post allowlisted", "POST", []string{"POST"}, true, []string{"payload", "payload", "payload"}, http.StatusOK},
+		{"put not allowlisted", "PUT", []string{"POST"}, false, []string{"payload"}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				bodies = append(bodies, string(b))
+				n := len(bodies)
+				mu.Unlock()
+				if n < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer srv.Close()
+
+			var body io.Reader
+			if tt.method != "GET" {
+				body = strings.NewReader("payload")
+				if tt.streamed {
+					body = io.MultiReader(body) // hides the *strings.Reader, so NewRequest can't set GetBody
+				}
+			}
+			req, err := http.NewRequest(tt.method, srv.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &http.Client{Transport: &RetryTransport{Attempts: 5, RetryMethods: tt.retryMethods, Logger: logger.Nop}}
+			res, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if strings.Join(bodies, "|") != strings.Join(tt.wantBodies, "|") {
+				t.Errorf("server got bodies %q, want %q", bodies, tt.wantBodies)
+			}
+		})
+	}
+}
+
+func TestRetryTransportNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close() // nothing listens there anymore
+
+	var attempts int
+	c := &http.Client{Transport: &RetryTransport{
+		Attempts:  3,
+		Logger:    logger.Nop,
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) { attempts++; return http.DefaultTransport.RoundTrip(r) }),
+	}}
+	if _, err := c.Get(url); err == nil {
+		t.Fatal("got a response from a closed server")
+	}
+	if attempts != 3 {
+		t.Errorf("%d attempts, want 3", attempts)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+A Server-Sent Events client that survives dropped connections.
+
+An SSE stream is a long lived GET answered with "Content-Type: text/event-stream",
+the server writes events as blocks of "field: value" lines, each block ended by an empty line:
+
+	id: 42
+	event: post
+	data: {"title":"hello"}
+
+Browsers have EventSource for this, which reconnects on its own and tells the server where it left off
+with a Last-Event-ID header. SSEClient does the same for Go programs, waiting Backoff between attempts.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// Event is one dispatched SSE event.
+type Event struct {
+	ID    string
+	Event string // "message" unless the server named it
+	Data  string // multiple data lines are joined with \n
+}
+
+type SSEClient struct {
+	Client  *http.Client // nil means http.DefaultClient, mind that a Client.Timeout also cuts off the stream
+	URL     string
+	Backoff Backoff       // delay before reconnect attempt n, nil means 1s then doubling up to 30s
+	Logger  logger.Logger // nil means logger.Default()
+
+	lastID string
+	retry  time.Duration // set by the server with "retry:", replaces Backoff once seen
+}
+
+// Subscribe connects and delivers events on the returned channel until ctx is done,
+// reconnecting whenever the stream breaks. The channel is closed once Subscribe gives up:
+// when ctx is done, or when the server answers 204 No Content, which is how it says "stop reconnecting". [1]
+func (c *SSEClient) Subscribe(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		lg := logger.Or(c.Logger)
+		backoff := c.Backoff
+		if backoff == nil {
+			backoff = ExponentialBackoff(time.Second, 30*time.Second)
+		}
+
+		for attempt := 0; ; attempt++ {
+			received, err := c.stream(ctx, events)
+			if ctx.Err() != nil || err == errStreamDone {
+				return
+			}
+			if received > 0 {
+				attempt = 0 // we were connected for a while, so start over from the shortest delay
+			}
+
+			delay := backoff.Delay(attempt)
+			if c.retry > 0 {
+				delay = c.retry
+			}
+			lg.Warn("sse stream lost, reconnecting", "url", c.URL, "err", err, "last_id", c.lastID, "delay", delay)
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+var errStreamDone = errors.New("sse: server asked us to stop reconnecting")
+
+// stream runs one connection, returning how many events it delivered and why it ended.
+func (c *SSEClient) stream(ctx context.Context, events chan<- Event) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if c.lastID != "" {
+		req.Header.Set("Last-Event-ID", c.lastID)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNoContent:
+		return 0, errStreamDone
+	case res.StatusCode != http.StatusOK:
+		return 0, fmt.Errorf("unexpected status: got %v", res.Status)
+	case !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream"):
+		return 0, fmt.Errorf("unexpected content type %q", res.Header.Get("Content-Type"))
+	}
+
+	var (
+		received int
+		ev       Event
+		data     []string
+		pending  = c.lastID // the id of the event being parsed, committed to lastID once it's dispatched [2]
+	)
+	sc := bufio.NewScanner(res.Body) // ScanLines accepts \n and \r\n, bare \r line endings aren't supported
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" { // end of the event, dispatch it [2]
+			c.lastID = pending // an id line counts even on an event without data, as in EventSource
+			if len(data) > 0 {
+				ev.ID = c.lastID
+				ev.Data = strings.Join(data, "\n")
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				select {
+				case events <- ev:
+					received++
+				case <-ctx.Done():
+					return received, ctx.Err()
+				}
+			}
+			ev, data = Event{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") { // a comment, servers send these as keep-alives
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			ev.Event = value
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				pending = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				c.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return received, err
+	}
+	return received, errors.New("sse: server closed the stream")
+}
+
+/*
+[1] : Reconnecting after any other status is a choice. EventSource gives up on everything but 200,
+			we keep retrying because a 502/503 from a proxy during a deploy is exactly the kind of outage a reconnect is for.
+
+[2] : An id applies from the event it came with onwards, so an event without an id line gets the last id seen.
+			That's also what goes into Last-Event-ID, so the server resumes right after the last event we delivered.
+			An id line only takes effect when its event is complete: an event cut off halfway by the broken connection
+			was never dispatched, so its id must not be the one we resume from, or the server would skip it.
+*/
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// sseServer serves events 1..total, resuming after Last-Event-ID. The first connection breaks off
+// after two events, in the middle of sending the third.
+func sseServer(t *testing.T, total int) (*httptest.Server, *atomic.Int32) {
+	var conns atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := conns.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		from := 1
+		if id := r.Header.Get("Last-Event-ID"); id != "" {
+			last, err := strconv.Atoi(id)
+			if err != nil {
+				t.Errorf("bad Last-Event-ID %q", id)
+			}
+			from = last + 1
+		}
+		fmt.Fprint(w, ": hello\nretry: 10\n\n")
+		for i := from; i <= total; i++ {
+			if n == 1 && i == 3 {
+				fmt.Fprintf(w, "id: %d\ndata: half of ev%d\n", i, i) // no blank line, the event never completes
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: tick\ndata: ev%d\ndata: line 2\n\n", i, i)
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done() // keep the stream open until the client leaves
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+func TestSSEClientReconnects(t *testing.T) {
+	srv, conns := sseServer(t, 5)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := &SSEClient{URL: srv.URL, Client: srv.Client(), Logger: logger.Nop}
+	events := c.Subscribe(ctx)
+	for i := 1; i <= 5; i++ {
+		ev, ok := <-events
+		if !ok {
+			t.Fatalf("channel closed after %d events", i-1)
+		}
+		want := Event{ID: strconv.Itoa(i), Event: "tick", Data: fmt.Sprintf("ev%d\nline 2", i)}
+		if ev != want {
+			t.Fatalf("event %d = %+v, want %+v", i, ev, want)
+		}
+	}
+	if n := conns.Load(); n != 2 {
+		t.Errorf("%d connections, want 2", n)
+	}
+
+	cancel()
+	for range events { // must be closed once ctx is done
+	}
+}
+
+func TestSSEClientStopsOn204(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := &SSEClient{URL: srv.URL, Logger: logger.Nop}
+	select {
+	case _, ok := <-c.Subscribe(context.Background()):
+		if ok {
+			t.Fatal("got an event from a 204")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe kept reconnecting after a 204")
+	}
+}
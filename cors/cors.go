@@ -0,0 +1,149 @@
+/*
+A hand-written CORS middleware.
+
+This mirrors the handful of rs/cors options we actually use, so we can see what the library does for us
+and fix one of its sharp edges along the way:
+
+- "Access-Control-Allow-Origin: *" is not allowed on credentialed requests. Browsers reject the response
+  if it is combined with "Access-Control-Allow-Credentials: true".
+- So when AllowedOrigins contains "*" and AllowCredentials is set, we run in "safe mode" and reflect the
+  request's Origin back instead of "*". [1]
+- If we'd rather fail loudly than be clever, set Strict and New returns ErrWildcardWithCredentials.
+*/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amitsuthar69/go-backend/internal/match"
+)
+
+var ErrWildcardWithCredentials = errors.New(`cors: wildcard origin "*" cannot be used with AllowCredentials`)
+
+type Options struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+	Strict           bool // error at construction instead of reflecting the origin in safe mode.
+}
+
+type Cors struct {
+	origins          *match.Matcher // exact origins, "*", or wildcard subdomains like "https://*.example.com"
+	allowAll         bool
+	reflectOrigin    bool
+	allowCredentials bool
+	methods          []string
+	headers          []string
+	maxAge           int
+}
+
+func New(opts Options) (*Cors, error) {
+	origins, err := match.Compile(opts.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cors{
+		origins:          origins,
+		allowAll:         origins.AllowsAll(),
+		allowCredentials: opts.AllowCredentials,
+		methods:          opts.AllowedMethods,
+		headers:          opts.AllowedHeaders,
+		maxAge:           opts.MaxAge,
+	}
+
+	if len(c.methods) == 0 {
+		c.methods = []string{"GET", "POST"} // same default as rs/cors
+	}
+
+	if c.allowAll && c.allowCredentials {
+		if opts.Strict {
+			return nil, ErrWildcardWithCredentials
+		}
+		c.reflectOrigin = true
+	}
+
+	return c, nil
+}
+
+func (c *Cors) originAllowed(origin string) bool {
+	return c.origins.Matches(origin)
+}
+
+// setOriginHeaders writes the origin related headers shared by preflight and actual requests.
+func (c *Cors) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if c.allowAll && !c.reflectOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *Cors) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+
+		// [2]
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			c.handlePreflight(w, r, origin)
+			return
+		}
+
+		if origin != "" && c.originAllowed(origin) {
+			c.setOriginHeaders(w, origin)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *Cors) methodAllowed(method string) bool {
+	for _, m := range c.methods {
+		if m == method { // [3]
+			return true
+		}
+	}
+	return false
+}
+
+// handlePreflight always answers the preflight itself, the actual handler never sees it.
+// The allow headers are only written when both the origin and the requested method are permitted,
+// otherwise the browser sees a bare 204 and blocks the real request.
+func (c *Cors) handlePreflight(w http.ResponseWriter, r *http.Request, origin string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	if origin != "" && c.originAllowed(origin) && c.methodAllowed(method) {
+		c.setOriginHeaders(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+		if len(c.headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.headers, ", "))
+		}
+		if c.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(c.maxAge))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+[1] : Reflecting the origin is what the browser would accept anyway, but it means *any* site can make
+			credentialed requests to us. It is "safe" only in the sense that the response is well-formed,
+			so prefer listing the concrete origins whenever we know them.
+
+[2] : The response differs depending on the Origin header, so caches must key on it.
+			Without "Vary: Origin" a CDN could serve a response meant for one origin to another.
+
+[3] : HTTP methods are case-sensitive, "delete" and "DELETE" are different methods.
+*/
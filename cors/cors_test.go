@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		wantErr     error
+		wantReflect bool
+	}{
+		{"listed origins", Options{AllowedOrigins: []string{"http://a.test"}, AllowCredentials: true}, nil, false},
+		{"wildcard without credentials", Options{AllowedOrigins: []string{"*"}}, nil, false},
+		{"wildcard with credentials, safe mode", Options{AllowedOrigins: []string{"*"}, AllowCredentials: true}, nil, true},
+		{"wildcard with credentials, strict", Options{AllowedOrigins: []string{"*"}, AllowCredentials: true, Strict: true}, ErrWildcardWithCredentials, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && c.reflectOrigin != tt.wantReflect {
+				t.Errorf("reflectOrigin = %v, want %v", c.reflectOrigin, tt.wantReflect)
+			}
+		})
+	}
+}
+
+func TestHandlerActualRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		origin    string
+		wantAllow string
+		wantCreds string
+	}{
+		{"listed origin", Options{AllowedOrigins: []string{"http://a.test"}}, "http://a.test", "http://a.test", ""},
+		{"unlisted origin", Options{AllowedOrigins: []string{"http://a.test"}}, "http://evil.test", "", ""},
+		{"no origin", Options{AllowedOrigins: []string{"http://a.test"}}, "", "", ""},
+		{"wildcard", Options{AllowedOrigins: []string{"*"}}, "http://any.test", "*", ""},
+		{"wildcard with credentials reflects", Options{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "http://any.test", "http://any.test", "true"},
+		{"credentials", Options{AllowedOrigins: []string{"http://a.test"}, AllowCredentials: true}, "http://a.test", "http://a.test", "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			c.Handler(okHandler).ServeHTTP(rec, req)
+
+			if rec.Body.String() != "ok" {
+				t.Error("the handler didn't run, CORS only tells the browser what it may read")
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			if got := rec.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+		})
+	}
+}
+
+func TestHandlerPreflight(t *testing.T) {
+	c, err := New(Options{
+		AllowedOrigins: []string{"http://a.test"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		origin    string
+		method    string
+		wantAllow bool
+	}{
+		{"allowed", "http://a.test", "PUT", true},
+		{"method not allowed", "http://a.test", "DELETE", false},
+		{"methods are case sensitive", "http://a.test", "put", false},
+		{"origin not allowed", "http://evil.test", "PUT", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("OPTIONS", "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			rec := httptest.NewRecorder()
+			c.Handler(okHandler).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+				t.Errorf("got %d %q, want a bare 204 without running the handler", rec.Code, rec.Body)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin") != ""; got != tt.wantAllow {
+				t.Fatalf("Allow-Origin set = %v, want %v", got, tt.wantAllow)
+			}
+			if tt.wantAllow {
+				if h.Get("Access-Control-Allow-Methods") != "GET, PUT" || h.Get("Access-Control-Allow-Headers") != "Content-Type" || h.Get("Access-Control-Max-Age") != "600" {
+					t.Errorf("preflight headers = %v", h)
+				}
+			} else if h.Get("Access-Control-Allow-Methods") != "" {
+				t.Errorf("Allow-Methods = %q on a refused preflight", h.Get("Access-Control-Allow-Methods"))
+			}
+			if got := h.Values("Vary"); len(got) != 3 {
+				t.Errorf("Vary = %v, want Origin and both request headers", got)
+			}
+		})
+	}
+
+	// A plain OPTIONS without Access-Control-Request-Method isn't a preflight, it reaches the handler.
+	rec := httptest.NewRecorder()
+	c.Handler(okHandler).ServeHTTP(rec, httptest.NewRequest("OPTIONS", "/", nil))
+	if rec.Body.String() != "ok" {
+		t.Errorf("plain OPTIONS didn't reach the handler: %d %q", rec.Code, rec.Body)
+	}
+}
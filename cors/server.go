@@ -35,14 +35,12 @@ package main
 import (
 	"log"
 	"net/http"
-
-	"github.com/rs/cors"
 )
 
 func main() {
 	mux := http.NewServeMux()
 
-	c := cors.New(cors.Options{
+	c, err := New(Options{
 		AllowedOrigins: []string{ // [1]
 			"http://localhost:8080",
 			"http://localhost:4321",
@@ -51,6 +49,9 @@ func main() {
 		AllowedMethods:   []string{"GET", "POST"}, // [3]
 		MaxAge:           86400,                   // [4]
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	handler := c.Handler(mux)
 
@@ -82,6 +83,9 @@ func main() {
 [4] : MaxAge int: Indicates how long (in seconds) the results of a preflight request can be cached.
 			The default is 0 which stands for no max age.
 
+The options above used to go straight to rs/cors, they are now handled by our own middleware in cors.go
+which also guards against the "*" + AllowCredentials combination.
+
 -------
 
 Usage :-
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// encryptedDB is a decorator too: it is a DB and wraps a DB, so the code storing values
+// can't tell it's there, yet nothing readable ever reaches the inner store.
+type encryptedDB struct {
+	db   DB
+	aead cipher.AEAD
+	err  error // a bad key, reported by every StoreToDB
+}
+
+// WithEncryption AES-GCM encrypts values before handing them to db, stored as base64(nonce + ciphertext).
+// key must be 16, 24 or 32 bytes (AES-128, -192 or -256), Decrypt with the same key reads them back.
+func WithEncryption(db DB, key []byte) DB {
+	aead, err := newGCM(key)
+	return &encryptedDB{db: db, aead: aead, err: err}
+}
+
+func (e *encryptedDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := e.aead.Seal(nonce, nonce, []byte(value), nil) // [1]
+	return e.db.StoreToDB(ctx, base64.StdEncoding.EncodeToString(sealed))
+}
+
+// Decrypt reverses WithEncryption on a stored value.
+func Decrypt(key []byte, stored string) (string, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("decrypt: value too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil) // fails if the value was tampered with, not just garbles it
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+/*
+[1] : A GCM nonce must never repeat for the same key, reusing one leaks the XOR of the two plaintexts
+			and lets anyone forge values. A fresh random nonce per value, stored in front of the ciphertext, avoids that.
+			Seal appends to its first argument, so passing the nonce gets us nonce + ciphertext in one slice.
+*/
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestWithEncryption(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   []byte
+		value string
+	}{
+		{"AES-128", bytes.Repeat([]byte("k"), 16), "hello"},
+		{"AES-192", bytes.Repeat([]byte("k"), 24), "hello"},
+		{"AES-256", bytes.Repeat([]byte("k"), 32), "hello"},
+		{"empty value", bytes.Repeat([]byte("k"), 32), ""},
+		{"long value", bytes.Repeat([]byte("k"), 32), strings.Repeat("secret ", 1000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingDB{id: "1"}
+			db := WithEncryption(inner, tt.key)
+
+			id, err := db.StoreToDB(context.Background(), tt.value)
+			if err != nil || id != "1" {
+				t.Fatalf("StoreToDB = %q, %v, want the inner store's id", id, err)
+			}
+			if _, err := db.StoreToDB(context.Background(), tt.value); err != nil {
+				t.Fatal(err)
+			}
+			stored := inner.values[0]
+			if stored == tt.value || (tt.value != "" && strings.Contains(stored, tt.value)) {
+				t.Errorf("stored %q, the plaintext reached the inner store", stored)
+			}
+			if inner.values[0] == inner.values[1] {
+				t.Error("the same value stored twice came out the same, the nonce isn't fresh")
+			}
+
+			for _, stored := range inner.values {
+				got, err := Decrypt(tt.key, stored)
+				if err != nil || got != tt.value {
+					t.Errorf("Decrypt = %q, %v, want %q", got, err, tt.value)
+				}
+			}
+		})
+	}
+}
+
+func TestWithEncryptionBadKey(t *testing.T) {
+	inner := &recordingDB{id: "1"}
+	if _, err := WithEncryption(inner, []byte("short")).StoreToDB(context.Background(), "hello"); err == nil {
+		t.Error("StoreToDB with a 5 byte key succeeded")
+	}
+	if len(inner.values) != 0 {
+		t.Errorf("inner store got %q, want nothing", inner.values)
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	inner := &recordingDB{}
+	if _, err := WithEncryption(inner, key).StoreToDB(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(inner.values[0])
+	sealed[len(sealed)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		key    []byte
+		stored string
+	}{
+		{"wrong key", bytes.Repeat([]byte("x"), 32), inner.values[0]},
+		{"tampered", key, base64.StdEncoding.EncodeToString(sealed)},
+		{"not base64", key, "!!!"},
+		{"too short", key, base64.StdEncoding.EncodeToString([]byte("abc"))},
+		{"bad key", []byte("short"), inner.values[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Decrypt(tt.key, tt.stored); err == nil {
+				t.Errorf("Decrypt = %q, want an error", got)
+			}
+		})
+	}
+}
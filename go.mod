@@ -2,6 +2,7 @@ module github.com/amitsuthar69/go-backend
 
 go 1.22.0
 
-require github.com/golang-jwt/jwt v3.2.2+incompatible
-
-require github.com/rs/cors v1.10.1
+require (
+	github.com/andybalholm/brotli v1.2.5
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+)
@@ -0,0 +1,83 @@
+/*
+Package logger is the logging interface the servers and the client take, instead of each
+reaching for fmt.Println or the global log package.
+
+The methods are *slog.Logger's, so any slog logger is a Logger as is:
+
+	var l logger.Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+args are alternating keys and values, l.Info("listening", "addr", ":4221").
+Passing Nop silences a component, passing a logger writing to a bytes.Buffer captures what it says.
+A nil Logger always means Default, use Or when reading one from a field.
+*/
+package logger
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// New returns a text Logger writing everything from level upwards to w.
+func New(w io.Writer, level slog.Level) Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Default is what components fall back to when they're given no Logger, Info and up on stderr.
+func Default() Logger {
+	return New(os.Stderr, slog.LevelInfo)
+}
+
+// Or returns l, or Default if l is nil.
+func Or(l Logger) Logger {
+	if l == nil {
+		return Default()
+	}
+	return l
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying l, for code that only gets a context (or a request) to log with.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Logger NewContext stored in ctx, or Default if there's none.
+func FromContext(ctx context.Context) Logger {
+	l, _ := ctx.Value(ctxKey{}).(Logger)
+	return Or(l)
+}
+
+type nop struct{}
+
+func (nop) Debug(string, ...any) {}
+func (nop) Info(string, ...any)  {}
+func (nop) Warn(string, ...any)  {}
+func (nop) Error(string, ...any) {}
+
+// Nop discards everything.
+var Nop Logger = nop{}
+
+// NewStdLog adapts l for APIs that want a *log.Logger, like http.Server.ErrorLog.
+// Every line is logged at Error level.
+func NewStdLog(l Logger) *log.Logger {
+	return log.New(writerFunc(func(b []byte) (int, error) {
+		l.Error(strings.TrimSuffix(string(b), "\n"))
+		return len(b), nil
+	}), "", 0)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) { return f(b) }
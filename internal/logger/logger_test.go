@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, slog.LevelInfo)
+
+	if got := FromContext(NewContext(context.Background(), l)); got != l {
+		t.Errorf("FromContext returned %v, want the stored logger", got)
+	}
+	if FromContext(context.Background()) == nil {
+		t.Error("FromContext of an empty context is nil, want Default")
+	}
+	if FromContext(NewContext(context.Background(), nil)) == nil {
+		t.Error("FromContext of a nil logger is nil, want Default")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		level   slog.Level
+		logged  func(Logger)
+		wantOut bool
+	}{
+		{slog.LevelInfo, func(l Logger) { l.Info("hi") }, true},
+		{slog.LevelInfo, func(l Logger) { l.Debug("hi") }, false},
+		{slog.LevelDebug, func(l Logger) { l.Debug("hi") }, true},
+		{slog.LevelError, func(l Logger) { l.Warn("hi") }, false},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		tt.logged(New(&buf, tt.level))
+		if got := strings.Contains(buf.String(), "msg=hi"); got != tt.wantOut {
+			t.Errorf("level %v: logged %q, want output %v", tt.level, buf.String(), tt.wantOut)
+		}
+	}
+}
+
+func TestNewStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	NewStdLog(New(&buf, slog.LevelInfo)).Print("http: TLS handshake error")
+	if out := buf.String(); !strings.Contains(out, "level=ERROR") || !strings.Contains(out, `msg="http: TLS handshake error"`) {
+		t.Errorf("got %q", out)
+	}
+}
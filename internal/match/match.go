@@ -0,0 +1,98 @@
+/*
+Package match implements the allowlist matching shared by the CORS middleware (Origin)
+and the HTTP server's Host allowlist, so both accept exactly the same pattern syntax:
+
+	"*"                        anything at all
+	"example.com"              exactly that value (case-insensitive)
+	"*.example.com"            any subdomain, e.g. "api.example.com" or "a.b.example.com", but not "example.com"
+	"https://*.example.com"    the same, with a fixed prefix
+
+The "*" only ever stands for DNS label characters (letters, digits, '-' and '.'),
+so "https://evil.com/?.example.com" can't sneak past "https://*.example.com".
+*/
+package match
+
+import (
+	"fmt"
+	"strings"
+)
+
+type pattern struct {
+	prefix, suffix string
+}
+
+type Matcher struct {
+	all      bool
+	exact    map[string]bool
+	wildcard []pattern
+}
+
+func Compile(patterns []string) (*Matcher, error) {
+	m := &Matcher{exact: make(map[string]bool)}
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch n := strings.Count(p, "*"); {
+		case p == "":
+			return nil, fmt.Errorf("match: empty pattern")
+		case p == "*":
+			m.all = true
+		case n == 0:
+			m.exact[p] = true
+		case n == 1:
+			prefix, suffix, _ := strings.Cut(p, "*")
+			if !strings.HasPrefix(suffix, ".") {
+				return nil, fmt.Errorf("match: %q: the wildcard must stand for whole subdomain labels, like *.example.com", p)
+			}
+			m.wildcard = append(m.wildcard, pattern{prefix, suffix})
+		default:
+			return nil, fmt.Errorf("match: %q: only one wildcard is supported", p)
+		}
+	}
+	return m, nil
+}
+
+// MustCompile is Compile for patterns known at compile time, it panics on an invalid pattern.
+func MustCompile(patterns []string) *Matcher {
+	m, err := Compile(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// AllowsAll reports whether the "*" pattern was given.
+func (m *Matcher) AllowsAll() bool {
+	return m.all
+}
+
+func (m *Matcher) Matches(value string) bool {
+	if m.all {
+		return true
+	}
+	value = strings.ToLower(value)
+	if m.exact[value] {
+		return true
+	}
+	for _, p := range m.wildcard {
+		if len(value) <= len(p.prefix)+len(p.suffix) || !strings.HasPrefix(value, p.prefix) || !strings.HasSuffix(value, p.suffix) {
+			continue
+		}
+		if isLabels(value[len(p.prefix) : len(value)-len(p.suffix)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLabels reports whether s is one or more DNS labels ("api", "a.b").
+func isLabels(s string) bool {
+	if s == "" || s[0] == '.' || s[0] == '-' || s[len(s)-1] == '.' { // a trailing dot would be "a..example.com" whole
+		return false
+	}
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return !strings.Contains(s, "..")
+}
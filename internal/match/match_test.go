@@ -0,0 +1,61 @@
+package match
+
+import "testing"
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		value    string
+		want     bool
+	}{
+		{"exact", []string{"example.com"}, "example.com", true},
+		{"exact is case-insensitive", []string{"Example.COM"}, "EXAMPLE.com", true},
+		{"exact needs the whole value", []string{"example.com"}, "api.example.com", false},
+		{"allow all", []string{"*"}, "anything://at.all", true},
+		{"allow all among others", []string{"example.com", "*"}, "evil.example", true},
+		{"wildcard subdomain", []string{"*.example.com"}, "api.example.com", true},
+		{"wildcard nested subdomain", []string{"*.example.com"}, "a.b.example.com", true},
+		{"wildcard wants a subdomain", []string{"*.example.com"}, "example.com", false},
+		{"wildcard with prefix", []string{"https://*.example.com"}, "https://app.example.com", true},
+		{"wildcard with prefix, other scheme", []string{"https://*.example.com"}, "http://app.example.com", false},
+		{"empty label", []string{"*.example.com"}, ".example.com", false},
+
+		// bypass attempts
+		{"suffix without the dot", []string{"*.example.com"}, "evilexample.com", false},
+		{"path hiding the suffix", []string{"https://*.example.com"}, "https://evil.com/?.example.com", false},
+		{"userinfo", []string{"https://*.example.com"}, "https://evil.com@x.example.com", false},
+		{"port in the wildcard", []string{"https://*.example.com"}, "https://evil.com:443.example.com", false},
+		{"double dot", []string{"*.example.com"}, "a..example.com", false},
+		{"leading dash", []string{"*.example.com"}, "-a.example.com", false},
+		{"exact prefix of a longer host", []string{"example.com"}, "example.com.evil.example", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Matches(tt.value); got != tt.want {
+				t.Errorf("%q matching %q = %v, want %v", tt.patterns, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, p := range []string{"", "  ", "*example.com", "*.*.example.com", "example.*"} {
+		if _, err := Compile([]string{p}); err == nil {
+			t.Errorf("Compile(%q) succeeded", p)
+		}
+	}
+}
+
+func TestAllowsAll(t *testing.T) {
+	if !MustCompile([]string{"a.com", "*"}).AllowsAll() {
+		t.Error(`AllowsAll with "*" = false`)
+	}
+	if MustCompile([]string{"*.a.com"}).AllowsAll() {
+		t.Error(`AllowsAll without "*" = true`)
+	}
+}
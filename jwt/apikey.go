@@ -0,0 +1,95 @@
+/*
+API key auth, for programs rather than people.
+
+The client sends a long random key it was issued, in a header or (when it can't set headers, e.g. a webhook URL)
+in the query string. There's no login step and no expiry, the key *is* the credential, so it's looked up
+on every request and revoking it means removing it from the store.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+// Principal is who a key belongs to.
+type Principal struct {
+	Name   string
+	Scopes []string
+}
+
+type KeyStore interface {
+	Lookup(key string) (Principal, bool)
+}
+
+// MapKeyStore is an in-memory KeyStore, key -> owner.
+type MapKeyStore map[string]Principal
+
+// Lookup compares key against every stored key in constant time, instead of indexing the map. [2]
+func (m MapKeyStore) Lookup(key string) (Principal, bool) {
+	var found Principal
+	ok := false
+	for k, p := range m {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			found, ok = p, true
+		}
+	}
+	return found, ok
+}
+
+// APIKeyAuth checks the key found in Header, or failing that in the Query parameter.
+// Empty names fall back to "X-API-Key" and "api_key".
+type APIKeyAuth struct {
+	Store  KeyStore
+	Header string
+	Query  string
+}
+
+// APIKeyMiddleware is APIKeyAuth with the default header and query parameter names.
+func APIKeyMiddleware(store KeyStore) func(http.Handler) http.Handler {
+	return APIKeyAuth{Store: store}.Middleware
+}
+
+func (a APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	header, query := a.Header, a.Query
+	if header == "" {
+		header = "X-API-Key"
+	}
+	if query == "" {
+		query = "api_key"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(header)
+		if key == "" {
+			key = r.URL.Query().Get(query) // [1]
+		}
+
+		p, ok := a.Store.Lookup(key)
+		if key == "" || !ok {
+			http.Error(w, "UnAuthorised User", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalKey, p)
+		ctx = context.WithValue(ctx, usernameKey, p.Name) // so handlers like Greet work with any of the auth middlewares
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PrincipalFrom returns the Principal APIKeyAuth authenticated the request as.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+/*
+[1] : URLs end up in access logs, browser history and Referer headers, a key in the query string should be
+			treated as leaked sooner rather than later. Prefer the header whenever the client can set one.
+
+[2] : m[key] hashes the key and then compares it with ==, which returns as soon as a byte differs: how long a
+			guess takes to be turned down leaks how much of it was right. The loop visits every key whatever matches,
+			fine for a handful of them. With many, store a hash of each key and look the hashed guess up instead.
+*/
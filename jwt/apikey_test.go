@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	store := MapKeyStore{"k-123": {Name: "ci", Scopes: []string{"deploy"}}}
+	tests := []struct {
+		name     string
+		auth     APIKeyAuth
+		header   [2]string // name, value
+		target   string
+		wantCode int
+		wantBody string
+	}{
+		{"header", APIKeyAuth{Store: store}, [2]string{"X-API-Key", "k-123"}, "/", http.StatusOK, "ci"},
+		{"query param", APIKeyAuth{Store: store}, [2]string{}, "/?api_key=k-123", http.StatusOK, "ci"},
+		{"custom names", APIKeyAuth{Store: store, Header: "X-Token", Query: "token"}, [2]string{"X-Token", "k-123"}, "/", http.StatusOK, "ci"},
+		{"custom query", APIKeyAuth{Store: store, Header: "X-Token", Query: "token"}, [2]string{}, "/?token=k-123", http.StatusOK, "ci"},
+		{"invalid key in header", APIKeyAuth{Store: store}, [2]string{"X-API-Key", "wrong"}, "/", http.StatusUnauthorized, ""},
+		{"invalid key in query", APIKeyAuth{Store: store}, [2]string{}, "/?api_key=nope", http.StatusUnauthorized, ""},
+		{"invalid header wins over query", APIKeyAuth{Store: store}, [2]string{"X-API-Key", "wrong"}, "/?api_key=k-123", http.StatusUnauthorized, ""},
+		{"custom header ignores the default", APIKeyAuth{Store: store, Header: "X-Token"}, [2]string{"X-API-Key", "k-123"}, "/",
+			http.StatusUnauthorized, ""},
+		{"missing", APIKeyAuth{Store: store}, [2]string{}, "/", http.StatusUnauthorized, ""},
+		{"empty key", APIKeyAuth{Store: MapKeyStore{"": {Name: "nobody"}}}, [2]string{}, "/?api_key=", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPrincipal Principal
+			h := tt.auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPrincipal, _ = PrincipalFrom(r.Context())
+				whoami(w, r)
+			}))
+
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header[0] != "" {
+				r.Header.Set(tt.header[0], tt.header[1])
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if gotPrincipal.Name != "ci" || len(gotPrincipal.Scopes) != 1 {
+				t.Errorf("PrincipalFrom = %+v, want the ci key's owner", gotPrincipal)
+			}
+		})
+	}
+}
+
+func TestMapKeyStoreLookup(t *testing.T) {
+	store := MapKeyStore{"key-amit": {Name: "amit"}, "key-bot": {Name: "bot", Scopes: []string{"read"}}}
+	tests := []struct {
+		key      string
+		wantName string
+		wantOK   bool
+	}{
+		{"key-amit", "amit", true},
+		{"key-bot", "bot", true},
+		{"key-ami", "", false},
+		{"key-amit2", "", false},
+		{"KEY-AMIT", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		p, ok := store.Lookup(tt.key)
+		if p.Name != tt.wantName || ok != tt.wantOK {
+			t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.key, p.Name, ok, tt.wantName, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestParseAuthorization(t *testing.T) {
+	tests := []struct {
+		header          string
+		wantScheme      string
+		wantCredentials string
+		wantErr         error
+	}{
+		{"Basic YW1pdDpwdw==", "basic", "YW1pdDpwdw==", nil},
+		{"Bearer abc.def.ghi", "bearer", "abc.def.ghi", nil},
+		{"bEaReR abc", "bearer", "abc", nil},
+		{"", "", "", ErrNoAuthorization},
+		{"Bearerabc", "", "", ErrMalformedAuthorization},
+		{"Bearer", "", "", ErrMalformedAuthorization},
+		{"Bearer  abc", "", "", ErrMalformedAuthorization},
+		{"Bearer abc def", "", "", ErrMalformedAuthorization},
+		{" abc", "", "", ErrMalformedAuthorization},
+	}
+	for _, tt := range tests {
+		scheme, credentials, err := ParseAuthorization(tt.header)
+		if scheme != tt.wantScheme || credentials != tt.wantCredentials || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseAuthorization(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.header, scheme, credentials, err, tt.wantScheme, tt.wantCredentials, tt.wantErr)
+		}
+	}
+}
+
+// whoami answers with the username the auth middleware put in the context.
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(Username(r.Context()))) })
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	basic := func(userpass string) string { return "Basic " + base64.StdEncoding.EncodeToString([]byte(userpass)) }
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+		wantBody string
+	}{
+		{"valid", basic("amit:juicewrld999"), http.StatusOK, "amit"},
+		{"wrong password", basic("amit:nope"), http.StatusUnauthorized, ""},
+		{"unknown user", basic("nobody:password"), http.StatusUnauthorized, ""},
+		{"no colon", basic("amit"), http.StatusUnauthorized, ""},
+		{"not base64", "Basic !!!", http.StatusUnauthorized, ""},
+		{"other scheme", "Bearer abc", http.StatusUnauthorized, ""},
+		{"missing", "", http.StatusUnauthorized, ""},
+	}
+	h := BasicAuthMiddleware(Users, whoami)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkAuth(t, h, tt.header, tt.wantCode, tt.wantBody, `Basic realm="go-backend"`)
+		})
+	}
+}
+
+func TestBearerAuthMiddleware(t *testing.T) {
+	sign := func(key []byte, expires time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			Username:       "amit",
+			StandardClaims: jwt.StandardClaims{ExpiresAt: expires.Unix()},
+		})
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+		wantBody string
+	}{
+		{"valid", "Bearer " + sign(jwtKey, time.Now().Add(time.Minute)), http.StatusOK, "amit"},
+		{"lowercase scheme", "bearer " + sign(jwtKey, time.Now().Add(time.Minute)), http.StatusOK, "amit"},
+		{"expired", "Bearer " + sign(jwtKey, time.Now().Add(-time.Minute)), http.StatusUnauthorized, ""},
+		{"wrong key", "Bearer " + sign([]byte("other"), time.Now().Add(time.Minute)), http.StatusUnauthorized, ""},
+		{"garbage", "Bearer abc", http.StatusUnauthorized, ""},
+		{"other scheme", "Basic abc", http.StatusUnauthorized, ""},
+	}
+	h := BearerAuthMiddleware(whoami)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkAuth(t, h, tt.header, tt.wantCode, tt.wantBody, `Bearer realm="go-backend"`)
+		})
+	}
+}
+
+func checkAuth(t *testing.T, h http.Handler, header string, wantCode int, wantBody, wantChallenge string) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/", nil)
+	if header != "" {
+		r.Header.Set("Authorization", header)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != wantCode {
+		t.Fatalf("status = %d, want %d", w.Code, wantCode)
+	}
+	if wantCode == http.StatusOK {
+		if w.Body.String() != wantBody {
+			t.Errorf("body = %q, want %q", w.Body.String(), wantBody)
+		}
+		return
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != wantChallenge {
+		t.Errorf("WWW-Authenticate = %q, want %q", got, wantChallenge)
+	}
+}
@@ -79,6 +79,10 @@ var Users = map[string]string{ // [2]
 	"user4": "123456",
 }
 
+var APIKeys = MapKeyStore{
+	"4f9c2a7e1b3d8f60": {Name: "ci-bot", Scopes: []string{"read"}},
+}
+
 type Credentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -168,10 +172,18 @@ func Home(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprintf("Hello, %s", claims.Username)))
 }
 
+// Greet is Home for the Authorization header based routes, the middleware has already done the checking.
+func Greet(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "Hello, %s", Username(r.Context()))
+}
+
 func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", Login)
 	mux.HandleFunc("/home", Home)
+	mux.Handle("/basic", BasicAuthMiddleware(Users, http.HandlerFunc(Greet)))
+	mux.Handle("/bearer", BearerAuthMiddleware(http.HandlerFunc(Greet)))
+	mux.Handle("/apikey", APIKeyMiddleware(APIKeys)(http.HandlerFunc(Greet)))
 
 	server := http.Server{
 		Addr:    ":3000",
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrBusy = errors.New("db: too many stores in flight")
+
+// limitedDB lets at most cap(sem) stores run at once, the way a connection pool caps a real DB's connections.
+type limitedDB struct {
+	db       DB
+	sem      chan struct{} // a buffered channel as a counting semaphore, a send takes a slot, a receive frees it
+	nonBlock bool
+}
+
+// WithConcurrencyLimit makes stores past max wait for a free slot, or for ctx to be done.
+// A max <= 0 means no limit, db is returned as is. [2]
+func WithConcurrencyLimit(db DB, max int) DB {
+	if max <= 0 {
+		return db
+	}
+	return &limitedDB{db: db, sem: make(chan struct{}, max)}
+}
+
+// WithConcurrencyLimitNonBlocking fails stores past max straight away with ErrBusy instead of queueing them. [1]
+// A max <= 0 means no limit here too.
+func WithConcurrencyLimitNonBlocking(db DB, max int) DB {
+	if max <= 0 {
+		return db
+	}
+	return &limitedDB{db: db, sem: make(chan struct{}, max), nonBlock: true}
+}
+
+func (l *limitedDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	if l.nonBlock {
+		select {
+		case l.sem <- struct{}{}:
+		default:
+			return "", ErrBusy
+		}
+	} else {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	defer func() { <-l.sem }()
+
+	return l.db.StoreToDB(ctx, value)
+}
+
+/*
+[1] : Waiting is fine for a burst, but under sustained overload the queue only grows and every caller
+			ends up waiting. Failing fast lets the caller shed the load, or try another replica, right away.
+
+[2] : A zero sized channel is unbuffered: every blocking store would wait on a send nobody receives
+			until its ctx gives up, and every non-blocking one would fail with ErrBusy.
+*/
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingDB counts the stores running at once, each one waits for release to be closed.
+type blockingDB struct {
+	running, peak atomic.Int32
+	release       chan struct{}
+}
+
+func (b *blockingDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	n := b.running.Add(1)
+	defer b.running.Add(-1)
+	for {
+		old := b.peak.Load()
+		if n <= old || b.peak.CompareAndSwap(old, n) {
+			break
+		}
+	}
+	<-b.release
+	return value, nil
+}
+
+func TestWithConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		wrap     func(DB) DB
+		wantPeak int32
+		wantBusy int
+	}{
+		{"blocking, max 2", func(db DB) DB { return WithConcurrencyLimit(db, 2) }, 2, 0},
+		{"blocking, max 0 is unlimited", func(db DB) DB { return WithConcurrencyLimit(db, 0) }, 5, 0},
+		{"non blocking, max 2", func(db DB) DB { return WithConcurrencyLimitNonBlocking(db, 2) }, 2, 3},
+		{"non blocking, max -1 is unlimited", func(db DB) DB { return WithConcurrencyLimitNonBlocking(db, -1) }, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &blockingDB{release: make(chan struct{})}
+			db := tt.wrap(inner)
+
+			var wg sync.WaitGroup
+			var busy atomic.Int32
+			for range 5 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+					defer cancel()
+					if _, err := db.StoreToDB(ctx, "v"); errors.Is(err, ErrBusy) {
+						busy.Add(1)
+					} else if err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			time.Sleep(50 * time.Millisecond) // let them all get as far as they can
+			close(inner.release)
+			wg.Wait()
+
+			if got := inner.peak.Load(); got != tt.wantPeak {
+				t.Errorf("%d stores at once, want %d", got, tt.wantPeak)
+			}
+			if got := int(busy.Load()); got != tt.wantBusy {
+				t.Errorf("%d ErrBusy, want %d", got, tt.wantBusy)
+			}
+		})
+	}
+}
+
+func TestWithConcurrencyLimitGivesUpWithCtx(t *testing.T) {
+	inner := &blockingDB{release: make(chan struct{})}
+	defer close(inner.release)
+	db := WithConcurrencyLimit(inner, 1)
+	go db.StoreToDB(context.Background(), "holds the only slot")
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := db.StoreToDB(ctx, "v"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
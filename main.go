@@ -1,35 +1,88 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Decorator Pattern
 
+// DB stores values. ctx lets the caller give up on a slow store,
+// the returned id is how the stored value is found again.
 type DB interface {
-	StoreToDB(string) error
+	StoreToDB(ctx context.Context, value string) (id string, err error)
 }
 
 type Store struct{}
 
-func (s *Store) StoreToDB(value string) error {
-	fmt.Println("Stored to DB", value)
-	return nil
+func (s *Store) StoreToDB(ctx context.Context, value string) (string, error) {
+	if err := ctx.Err(); err != nil { // don't start work nobody is waiting for anymore
+		return "", err
+	}
+	id := newID()
+	fmt.Println("Stored to DB", value, "as", id)
+	return id, nil
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 func myFunction(db DB) function {
-	return func (s string) {
+	return func(ctx context.Context, s string) (string, error) {
 		fmt.Println(s)
-		db.StoreToDB(s)
+		return db.StoreToDB(ctx, s)
+	}
+}
+
+// WithConditionalStore runs fn first and only stores the value if fn succeeded,
+// so a failed operation never gets persisted.
+func WithConditionalStore(db DB, fn func(string) error) function {
+	return func(ctx context.Context, s string) (string, error) {
+		if err := fn(s); err != nil {
+			fmt.Println("Not stored", s, ":", err)
+			return "", err
+		}
+		return db.StoreToDB(ctx, s)
+	}
+}
+
+func notEmpty(s string) error {
+	if s == "" {
+		return errors.New("empty value")
 	}
+	return nil
 }
 
 func main() {
+	ctx := context.Background()
 	s := &Store{}
-	Execute(myFunction(s))
+	Execute(ctx, myFunction(s))
+	Execute(ctx, WithConditionalStore(s, notEmpty))
+	Execute(ctx, myFunction(&MultiStore{Backends: []DB{s, &Store{}}, Concurrent: true}))
+	Execute(ctx, myFunction(WithEncryption(s, []byte("0123456789abcdef0123456789abcdef"))))
+	Execute(ctx, myFunction(WithAudit(s, PrintAuditSink{})))
+	Execute(ctx, myFunction(WithRetryDB(s, 3, func(attempt int) time.Duration {
+		return time.Duration(attempt+1) * 100 * time.Millisecond // 100ms, 200ms...
+	})))
+	Execute(ctx, myFunction(WithBreakerDB(s, 3, 5*time.Second)))
+	Execute(ctx, myFunction(WithConcurrencyLimit(s, 4)))
 }
 
 // third party function
-type function func(string)
+type function func(ctx context.Context, value string) (id string, err error)
 
-func Execute(fn function) {
-	fn("FOO BAR BAZ")
+func Execute(ctx context.Context, fn function) {
+	id, err := fn(ctx, "FOO BAR BAZ")
+	if err != nil {
+		fmt.Println("Execute failed:", err)
+		return
+	}
+	fmt.Println("Execute stored", id)
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestStoreToDB(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"stores", context.Background(), nil},
+		{"canceled", canceled, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := (&Store{}).StoreToDB(tt.ctx, "hello")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(id) != 8 {
+				t.Errorf("id = %q, want 8 hex digits", id)
+			}
+			if tt.wantErr != nil && id != "" {
+				t.Errorf("id = %q for a failed store", id)
+			}
+		})
+	}
+
+	a, _ := (&Store{}).StoreToDB(context.Background(), "x")
+	b, _ := (&Store{}).StoreToDB(context.Background(), "x")
+	if a == b {
+		t.Errorf("two stores got the same id %q", a)
+	}
+}
+
+// The functions handed to Execute pass the DB's id and errors through untouched.
+func TestFunctionsPropagateTheID(t *testing.T) {
+	errDown := errors.New("db is down")
+	errRejected := errors.New("rejected")
+	tests := []struct {
+		name       string
+		db         *recordingDB
+		fn         func(DB) function
+		wantID     string
+		wantErr    error
+		wantStored int
+	}{
+		{"myFunction", &recordingDB{id: "id-1"}, myFunction, "id-1", nil, 1},
+		{"myFunction failing", &recordingDB{err: errDown}, myFunction, "", errDown, 1},
+		{"conditional store", &recordingDB{id: "id-2"}, func(db DB) function {
+			return WithConditionalStore(db, notEmpty)
+		}, "id-2", nil, 1},
+		{"conditional store rejecting", &recordingDB{id: "id-3"}, func(db DB) function {
+			return WithConditionalStore(db, func(string) error { return errRejected })
+		}, "", errRejected, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.fn(tt.db)(context.Background(), "hello")
+			if id != tt.wantID || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %q, %v, want %q, %v", id, err, tt.wantID, tt.wantErr)
+			}
+			if len(tt.db.values) != tt.wantStored {
+				t.Errorf("stored %q, want %d values", tt.db.values, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestWithConditionalStore(t *testing.T) {
+	errDown := errors.New("db is down")
+	tests := []struct {
+		name       string
+		value      string
+		dbErr      error
+		wantErr    bool
+		wantStored []string
+	}{
+		{"condition holds", "hello", nil, false, []string{"hello"}},
+		{"condition fails", "", nil, true, nil},
+		{"condition holds, store fails", "hello", errDown, true, []string{"hello"}},
+		{"condition fails, store never tried", "", errDown, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &recordingDB{id: "id-1", err: tt.dbErr}
+			var checked []string
+			fn := WithConditionalStore(db, func(s string) error {
+				checked = append(checked, s)
+				if len(db.values) > 0 {
+					t.Error("the condition ran after the store")
+				}
+				return notEmpty(s)
+			})
+
+			id, err := fn(context.Background(), tt.value)
+			if (err != nil) != tt.wantErr || (err == nil && id != "id-1") {
+				t.Errorf("got %q, %v, want an error: %v", id, err, tt.wantErr)
+			}
+			if len(checked) != 1 || checked[0] != tt.value {
+				t.Errorf("condition saw %q, want just %q", checked, tt.value)
+			}
+			if !slices.Equal(db.values, tt.wantStored) {
+				t.Errorf("stored %q, want %q", db.values, tt.wantStored)
+			}
+		})
+	}
+}
+
+// A ctx canceled on the way reaches the Store through any decorator.
+func TestCancellationThroughDecorators(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, db := range map[string]DB{
+		"plain":      &Store{},
+		"encrypted":  WithEncryption(&Store{}, make([]byte, 32)),
+		"audited":    WithAudit(&Store{}, &sliceAuditSink{}),
+		"multistore": &MultiStore{Backends: []DB{&Store{}, &Store{}}},
+	} {
+		if _, err := myFunction(db)(ctx, "hello"); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: err = %v, want context.Canceled", name, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// BackoffFunc says how long to wait before retry number attempt (0 for the first retry).
+type BackoffFunc func(attempt int) time.Duration
+
+type retryDB struct {
+	db       DB
+	attempts int
+	backoff  BackoffFunc
+}
+
+// WithRetryDB retries failed stores, up to attempts tries in total, waiting backoff between them.
+// A cancelled ctx stops it at once, mid-wait included, with ctx's error. [1]
+func WithRetryDB(db DB, attempts int, backoff BackoffFunc) DB {
+	return &retryDB{db: db, attempts: max(attempts, 1), backoff: backoff}
+}
+
+func (r *retryDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		id, err := r.db.StoreToDB(ctx, value)
+		if err == nil || attempt+1 >= r.attempts || ctx.Err() != nil {
+			return id, err
+		}
+
+		var delay time.Duration
+		if r.backoff != nil {
+			delay = r.backoff(attempt)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		}
+	}
+}
+
+/*
+[1] : Retrying blindly is only safe if storing the same value twice is harmless. If the first attempt did reach
+			the DB but its reply got lost, the retry stores a duplicate. See IdempotencyMiddleware in the server for
+			how a key sent along with the value lets the receiving side spot the repeat.
+*/
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyDB answers its calls with errs in order, and succeeds once they run out.
+type flakyDB struct {
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (f *flakyDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return "", err
+	}
+	return "id-" + value, nil
+}
+
+func TestWithRetryDB(t *testing.T) {
+	errFlaky := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		errs      []error
+		attempts  int
+		wantID    string
+		wantErr   error
+		wantCalls int
+		wantWaits []int // the attempt numbers backoff was asked about
+	}{
+		{"first try", nil, 3, "id-v", nil, 1, nil},
+		{"transient failure", []error{errFlaky}, 3, "id-v", nil, 2, []int{0}},
+		{"succeeds on the last try", []error{errFlaky, errFlaky}, 3, "id-v", nil, 3, []int{0, 1}},
+		{"out of attempts", []error{errFlaky, errFlaky, errFlaky}, 3, "", errFlaky, 3, []int{0, 1}},
+		{"attempts below 1 still tries once", []error{errFlaky}, 0, "", errFlaky, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyDB{errs: tt.errs}
+			var waits []int
+			db := WithRetryDB(inner, tt.attempts, func(attempt int) time.Duration {
+				waits = append(waits, attempt)
+				return time.Millisecond
+			})
+
+			id, err := db.StoreToDB(context.Background(), "v")
+			if id != tt.wantID || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %q, %v, want %q, %v", id, err, tt.wantID, tt.wantErr)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("%d calls, want %d", inner.calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("backoff asked for %v, want %v", waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+// Cancelling mid-wait ends the retry loop right away instead of sleeping out the backoff.
+func TestWithRetryDBCanceled(t *testing.T) {
+	inner := &flakyDB{errs: []error{errors.New("down"), errors.New("down")}}
+	db := WithRetryDB(inner, 5, func(int) time.Duration { return time.Minute })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	_, err := db.StoreToDB(ctx, "v")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if took := time.Since(begin); took > time.Second {
+		t.Errorf("took %v, want it to stop when ctx did", took)
+	}
+	if inner.calls != 1 {
+		t.Errorf("%d calls, want 1", inner.calls)
+	}
+}
+
+// A store that fails because ctx is done isn't retried.
+func TestWithRetryDBCanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inner := &flakyDB{errs: []error{context.Canceled}}
+	if _, err := WithRetryDB(inner, 5, nil).StoreToDB(ctx, "v"); !errors.Is(err, context.Canceled) || inner.calls != 1 {
+		t.Errorf("err = %v after %d calls, want context.Canceled after 1", err, inner.calls)
+	}
+}
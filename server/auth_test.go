@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Known keys set the principal, everything else goes through as anonymous, nothing is rejected.
+func TestPrincipalFromAPIKeyMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		key           string
+		wantPrincipal string
+	}{
+		{"known key", "demo-key-amit", "amit"},
+		{"another known key", "demo-key-guest", "guest"},
+		{"no key", "", ""},
+		{"unknown key", "demo-key-nobody", ""},
+		{"prefix of a known key", "demo-key-", ""},
+		{"case differs", "DEMO-KEY-AMIT", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := "not called"
+			h := PrincipalFromAPIKeyMiddleware(APIKeys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = Principal(r.Context())
+			}))
+			r := httptest.NewRequest("GET", "/posts", nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK || principal != tt.wantPrincipal {
+				t.Errorf("got %d, principal %q, want 200, principal %q", w.Code, principal, tt.wantPrincipal)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Batch endpoints with partial success.
+
+One request carries many operations, and one bad item shouldn't throw away the work done for the others.
+So every item gets its own outcome and the response as a whole is a 207 Multi-Status:
+	[
+		{"index": 0, "status": 201, "result": {...}},
+		{"index": 1, "status": 400, "error": "title is required"},
+		{"index": 2, "status": 201, "result": {...}}
+	]
+The client has to look at each item's status, a 207 on its own says nothing about success.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+const maxBatchItems = 100
+
+type BatchResult[R any] struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Result *R     `json:"result,omitempty"` // a pointer, so failed items leave it out instead of sending a zero R
+	Error  string `json:"error,omitempty"`
+}
+
+// ProcessBatch runs fn on every item in order, carrying on past failures. Successful items get okStatus,
+// failed ones the status of their statusError, or a 500 (with the details logged, not returned).
+func ProcessBatch[T, R any](ctx context.Context, items []T, okStatus int, fn func(context.Context, T) (R, error)) []BatchResult[R] {
+	results := make([]BatchResult[R], len(items))
+	for i, item := range items {
+		results[i].Index = i
+		if err := ctx.Err(); err != nil { // the client is gone, no point doing the rest
+			results[i].Status, results[i].Error = http.StatusServiceUnavailable, "batch aborted"
+			continue
+		}
+
+		res, err := fn(ctx, item)
+		if err != nil {
+			var se *statusError
+			if errors.As(err, &se) {
+				results[i].Status, results[i].Error = se.status, se.Error()
+			} else {
+				logger.FromContext(ctx).Error("batch item failed", "request_id", RequestID(ctx), "index", i, "err", err)
+				results[i].Status, results[i].Error = http.StatusInternalServerError, "Internal Server Error"
+			}
+			continue
+		}
+		results[i].Status, results[i].Result = okStatus, &res
+	}
+	return results
+}
+
+// BatchHandler decodes a JSON array of T, runs it through ProcessBatch and answers the 207.
+func BatchHandler[T, R any](okStatus int, fn func(context.Context, T) (R, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items []T
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&items); err != nil {
+			writeError(w, r, badRequest(errors.New("body must be a JSON array")))
+			return
+		}
+		if len(items) > maxBatchItems {
+			writeError(w, r, badRequest(fmt.Errorf("at most %d items per batch", maxBatchItems)))
+			return
+		}
+
+		writeJSON(w, http.StatusMultiStatus, ProcessBatch(r.Context(), items, okStatus, fn))
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestBatchHandler(t *testing.T) {
+	double := func(ctx context.Context, n int) (int, error) {
+		switch n {
+		case 0:
+			return 0, badRequest(errors.New("zero is not allowed"))
+		case 13:
+			return 0, errors.New("db is down") // not for the client's eyes
+		}
+		return 2 * n, nil
+	}
+	result := func(v int) *int { return &v }
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantItems  []BatchResult[int]
+	}{
+		{"middle one fails", `[1, 0, 3]`, http.StatusMultiStatus, []BatchResult[int]{
+			{Index: 0, Status: http.StatusCreated, Result: result(2)},
+			{Index: 1, Status: http.StatusBadRequest, Error: "zero is not allowed"},
+			{Index: 2, Status: http.StatusCreated, Result: result(6)},
+		}},
+		{"internal error", `[13, 4]`, http.StatusMultiStatus, []BatchResult[int]{
+			{Index: 0, Status: http.StatusInternalServerError, Error: "Internal Server Error"},
+			{Index: 1, Status: http.StatusCreated, Result: result(8)},
+		}},
+		{"empty", `[]`, http.StatusMultiStatus, []BatchResult[int]{}},
+		{"not an array", `{"n": 1}`, http.StatusBadRequest, nil},
+		{"too many items", "[" + strings.Repeat("1,", maxBatchItems) + "1]", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := LoggerMiddleware(logger.Nop)(BatchHandler(http.StatusCreated, double))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/posts/batch", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantItems == nil {
+				return
+			}
+			var got []BatchResult[int]
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.wantItems) {
+				t.Errorf("items = %s, want %+v", w.Body, tt.wantItems)
+			}
+		})
+	}
+}
+
+// Once the client is gone the remaining items are skipped, not run.
+func TestProcessBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran []int
+	results := ProcessBatch(ctx, []int{1, 2, 3}, http.StatusOK, func(ctx context.Context, n int) (int, error) {
+		ran = append(ran, n)
+		if n == 1 {
+			cancel()
+		}
+		return n, nil
+	})
+
+	if !reflect.DeepEqual(ran, []int{1}) {
+		t.Errorf("ran %v, want only the first item", ran)
+	}
+	for i, want := range []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusServiceUnavailable} {
+		if results[i].Status != want {
+			t.Errorf("item %d status = %d, want %d", i, results[i].Status, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BindForm fills the struct dst points to from a form-encoded body, field by field through `form` tags:
+//
+//	type signup struct {
+//		Email string `form:"email,required"`
+//		Age   int    `form:"age"`
+//		Tags  []string `form:"tag"` // repeated ?tag=a&tag=b values
+//	}
+//
+// Untagged fields are left alone. Missing or unparsable values come back as ValidationErrors,
+// which writeError answers with a 422 listing all of them.
+func BindForm(r *http.Request, dst any) error {
+	if err := r.ParseForm(); err != nil {
+		return badRequest(err)
+	}
+	return bindValues(r.PostForm, dst, "form") // PostForm: body only, r.Form would mix the query string in [1]
+}
+
+func bindValues(values url.Values, dst any, tag string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("bind: dst must be a pointer to a struct") // a programming error, hence no status
+	}
+	v = v.Elem()
+
+	var errs ValidationErrors
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok || len(raw) == 0 || raw[0] == "" {
+			if opts == "required" {
+				errs = append(errs, FieldError{name, "required", "is required"})
+			}
+			continue
+		}
+
+		if err := setField(v.Field(i), raw); err != nil {
+			errs = append(errs, FieldError{name, "invalid", err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil // not errs, a nil ValidationErrors in an error interface isn't a nil error
+}
+
+func setField(f reflect.Value, raw []string) error {
+	if f.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(f.Type(), len(raw), len(raw))
+		for i, value := range raw {
+			if err := setScalar(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	return setScalar(f, raw[0])
+}
+
+func setScalar(f reflect.Value, value string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits()) // the bit size catches 300 for an int8
+		if err != nil {
+			return errors.New("must be an integer in range")
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer in range")
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+/*
+[1] : With r.Form a value in the URL would fill the field whenever the body left it out,
+			so a link to ?role=admin could smuggle a field into somebody else's form post.
+*/
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type signupForm struct {
+	Email    string   `form:"email,required"`
+	Age      int8     `form:"age"`
+	Score    float64  `form:"score"`
+	Admin    bool     `form:"admin"`
+	Tags     []string `form:"tag"`
+	Ids      []uint   `form:"id"`
+	Note     string   // untagged, left alone
+	Ignored  string   `form:"-"`
+	internal string   `form:"internal"`
+}
+
+func TestBindForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		want     signupForm
+		wantErrs []FieldError
+	}{
+		{"every type", "/signup", "email=a%40b.c&age=42&score=1.5&admin=true&tag=x&tag=y&id=1&id=2&Note=n&Ignored=i&internal=i",
+			signupForm{Email: "a@b.c", Age: 42, Score: 1.5, Admin: true, Tags: []string{"x", "y"}, Ids: []uint{1, 2}}, nil},
+		{"optional fields missing", "/signup", "email=a%40b.c",
+			signupForm{Email: "a@b.c"}, nil},
+		{"missing required", "/signup", "age=42", signupForm{}, []FieldError{
+			{"email", "required", "is required"},
+		}},
+		{"empty counts as missing", "/signup", "email=", signupForm{}, []FieldError{
+			{"email", "required", "is required"},
+		}},
+		{"query string doesn't count", "/signup?email=a%40b.c", "", signupForm{}, []FieldError{
+			{"email", "required", "is required"},
+		}},
+		{"every error listed", "/signup", "age=300&score=x&admin=maybe&id=-1", signupForm{}, []FieldError{
+			{"email", "required", "is required"},
+			{"age", "invalid", "must be an integer in range"},
+			{"score", "invalid", "must be a number"},
+			{"admin", "invalid", "must be true or false"},
+			{"id", "invalid", "must be a non-negative integer in range"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			var got signupForm
+			err := BindForm(r, &got)
+			if tt.wantErrs == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("bound %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+
+			var ve ValidationErrors
+			if !errors.As(err, &ve) {
+				t.Fatalf("err = %v, want ValidationErrors", err)
+			}
+			if !reflect.DeepEqual([]FieldError(ve), tt.wantErrs) {
+				t.Errorf("errors = %+v, want %+v", ve, tt.wantErrs)
+			}
+			w := httptest.NewRecorder()
+			writeError(w, r, err)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("writeError status = %d, want 422", w.Code)
+			}
+		})
+	}
+}
+
+func TestBindFormBadDst(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader("email=x"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var s signupForm
+	for _, dst := range []any{s, new(string), nil} {
+		if err := BindForm(r, dst); err == nil {
+			t.Errorf("BindForm(%T) succeeded, want an error", dst)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestBotFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		tagOnly    bool
+		disabled   bool
+		path, ua   string
+		wantStatus int
+		wantTag    string
+	}{
+		{"browser", false, false, "/posts", "Mozilla/5.0 (X11; Linux x86_64)", http.StatusOK, ""},
+		{"empty user agent", false, false, "/posts", "", http.StatusForbidden, ""},
+		{"blank user agent", false, false, "/posts", "   ", http.StatusForbidden, ""},
+		{"allowlisted path", false, false, "/metrics", "", http.StatusOK, ""},
+		{"allowlisted prefix", false, false, "/metrics/slo", "", http.StatusOK, ""},
+		{"denylisted user agent", false, false, "/posts", "sqlmap/1.7#stable (https://sqlmap.org)", http.StatusForbidden, ""},
+		{"denylist ignores case", false, false, "/posts", "Mozilla/5.0 Nikto/2.5", http.StatusForbidden, ""},
+		{"denylist applies on allowlisted paths", false, false, "/metrics", "nuclei", http.StatusForbidden, ""},
+		{"tag only, empty", true, false, "/posts", "", http.StatusOK, "empty user agent"},
+		{"tag only, denied", true, false, "/posts", "masscan/1.3", http.StatusOK, "denied user agent"},
+		{"disabled", false, true, "/posts", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &BotFilter{Deny: BadBotPatterns, AllowEmptyUA: []string{"/metrics"}, TagOnly: tt.tagOnly, Logger: logger.Nop}
+			if tt.disabled {
+				f.SetEnabled(false)
+			}
+			tag := "not called"
+			h := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tag = SuspectedBot(r.Context())
+			}))
+
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.ua != "" {
+				r.Header.Set("User-Agent", tt.ua)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && tag != tt.wantTag {
+				t.Errorf("SuspectedBot = %q, want %q", tag, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestBotFilterToggle(t *testing.T) {
+	f := &BotFilter{Logger: logger.Nop}
+	h := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func() int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Code
+	}
+
+	for _, step := range []struct {
+		on   bool
+		want int
+	}{{false, http.StatusOK}, {true, http.StatusForbidden}, {false, http.StatusOK}} {
+		f.SetEnabled(step.on)
+		if got := status(); got != step.want {
+			t.Errorf("enabled %v: status = %d, want %d", step.on, got, step.want)
+		}
+	}
+}
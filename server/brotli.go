@@ -0,0 +1,22 @@
+//go:build brotli
+
+package main
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Built with -tags brotli, so the default build doesn't pull in the dependency.
+func init() {
+	supportedEncodings = append([]string{"br"}, supportedEncodings...) // preferred over gzip on equal q values
+	compressors["br"] = func(w io.Writer) io.WriteCloser {
+		return brotli.NewWriterLevel(w, 5) // [1]
+	}
+}
+
+/*
+[1] : Brotli's levels go from 0 to 11. 11 squeezes out a few more percent but is far too slow to run
+			on every response, it's meant for compressing static files once ahead of time. 4-6 is the usual sweet spot.
+*/
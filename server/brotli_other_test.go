@@ -0,0 +1,22 @@
+//go:build !brotli
+
+package main
+
+import "testing"
+
+// Without -tags brotli a client preferring br gets the next best thing.
+func TestCompressMiddlewareWithoutBrotli(t *testing.T) {
+	tests := []struct {
+		accept       string
+		wantEncoding string
+	}{
+		{"br, gzip;q=0.5", "gzip"},
+		{"gzip", "gzip"},
+		{"br", ""},
+	}
+	for _, tt := range tests {
+		if got := compressedResponse(tt.accept).Header().Get("Content-Encoding"); got != tt.wantEncoding {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", tt.accept, got, tt.wantEncoding)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+//go:build brotli
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressMiddlewareBrotli(t *testing.T) {
+	tests := []struct {
+		accept       string
+		wantEncoding string
+	}{
+		{"br", "br"},
+		{"gzip, br", "br"}, // equal q values, br is preferred
+		{"br;q=1, gzip;q=0.5", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"gzip", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"*", "br"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			rec := compressedResponse(tt.accept)
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+
+			var r io.Reader = brotli.NewReader(rec.Body)
+			if tt.wantEncoding == "gzip" {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = gz
+			}
+			if b, err := io.ReadAll(r); err != nil || string(b) != compressedBody {
+				t.Errorf("decoded body = %q, %v", b, err)
+			}
+		})
+	}
+}
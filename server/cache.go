@@ -0,0 +1,140 @@
+/*
+A response cache with request coalescing for GET handlers.
+
+- A response is kept for ttl and replayed to every later request with the same key.
+- Requests arriving while the first one for a key is still running wait for it and share its response,
+  instead of all hitting the handler at once ("single flight"). That's what saves an expensive handler
+  from a thundering herd right after its entry expires.
+- Only 200 responses are cached, errors are handed to the waiters once but not remembered.
+
+The key decides who shares what. By default it's method + path + query, fine for public data.
+For anything personalised pass a key func that includes the user, HeaderCacheKey does that with a header. [1]
+*/
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	done    chan struct{} // closed once the fields below are filled in
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	key     func(r *http.Request) string
+	entries map[string]*cacheEntry
+}
+
+// CacheMiddleware caches GET responses for ttl under key(r), nil key means DefaultCacheKey.
+func CacheMiddleware(ttl time.Duration, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	if key == nil {
+		key = DefaultCacheKey
+	}
+	c := &responseCache{ttl: ttl, key: key, entries: make(map[string]*cacheEntry)}
+	return c.middleware
+}
+
+// DefaultCacheKey is method + path + query.
+func DefaultCacheKey(r *http.Request) string {
+	return r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery
+}
+
+// HeaderCacheKey keys on the value of header (say a user id set by the auth middleware) plus DefaultCacheKey,
+// so every user gets their own entries.
+func HeaderCacheKey(header string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(header) + " " + DefaultCacheKey(r)
+	}
+}
+
+// lookup returns the entry for key, or registers a new in-flight one (owner == true) if there's none.
+func (c *responseCache) lookup(key string) (entry *cacheEntry, owner bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range c.entries { // lazy cleanup, same as the idempotency cache
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if e, ok := c.entries[key]; ok {
+		return e, false
+	}
+	e := &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	return e, true
+}
+
+func (c *responseCache) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := c.key(r)
+
+		entry, owner := c.lookup(key)
+		if owner {
+			c.run(next, w, r, key, entry)
+			return
+		}
+
+		select {
+		case <-entry.done:
+		case <-r.Context().Done():
+			return
+		}
+		for k, v := range entry.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("X-Cache", "HIT")
+		w.WriteHeader(entry.status)
+		w.Write(entry.body)
+	})
+}
+
+func (c *responseCache) run(next http.Handler, w http.ResponseWriter, r *http.Request, key string, entry *cacheEntry) {
+	w.Header().Set("X-Cache", "MISS")
+	rec := &teeRecorder{ResponseWriter: w}
+	defer func() {
+		p := recover()
+		c.mu.Lock()
+		switch {
+		case p != nil:
+			rec.status, rec.header = http.StatusInternalServerError, nil // the waiters answer 500 too, RecoverMiddleware deals with this one
+			rec.body.Reset()
+		case rec.status == 0:
+			rec.status = http.StatusOK
+		}
+		entry.status = rec.status
+		entry.header = rec.header
+		entry.body = rec.body.Bytes()
+		if rec.status == http.StatusOK {
+			entry.expires = time.Now().Add(c.ttl)
+		} else {
+			delete(c.entries, key) // the waiters already hold the entry, they still get this response
+		}
+		c.mu.Unlock()
+		close(entry.done)
+		if p != nil {
+			panic(p)
+		}
+	}()
+	next.ServeHTTP(rec, r)
+}
+
+/*
+[1] : Getting the key wrong is a data leak, not a performance bug: with the default key the second user
+			asking for /user gets the first user's profile. When in doubt, don't cache.
+*/
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	type request struct {
+		method, target, user string
+		wantBody, wantCache  string
+	}
+	tests := []struct {
+		name     string
+		key      func(*http.Request) string
+		requests []request
+	}{
+		{"per user key", HeaderCacheKey("X-User"), []request{
+			{"GET", "/profile", "amit", "call 1 for amit", "MISS"},
+			{"GET", "/profile", "kanye", "call 2 for kanye", "MISS"},
+			{"GET", "/profile", "amit", "call 1 for amit", "HIT"},
+			{"GET", "/profile", "kanye", "call 2 for kanye", "HIT"},
+		}},
+		{"default key shares between users", nil, []request{
+			{"GET", "/profile", "amit", "call 1 for amit", "MISS"},
+			{"GET", "/profile", "kanye", "call 1 for amit", "HIT"},
+			{"GET", "/profile?page=2", "kanye", "call 2 for kanye", "MISS"},
+		}},
+		{"only GET is cached", nil, []request{
+			{"POST", "/profile", "amit", "call 1 for amit", ""},
+			{"POST", "/profile", "amit", "call 2 for amit", ""},
+		}},
+		{"errors aren't cached", nil, []request{
+			{"GET", "/fail", "amit", "call 1 for amit", "MISS"},
+			{"GET", "/fail", "amit", "call 2 for amit", "MISS"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			h := CacheMiddleware(time.Minute, tt.key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/fail" {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+				fmt.Fprintf(w, "call %d for %s", calls.Add(1), r.Header.Get("X-User"))
+			}))
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(req.method, req.target, nil)
+				r.Header.Set("X-User", req.user)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if w.Body.String() != req.wantBody || w.Header().Get("X-Cache") != req.wantCache {
+					t.Errorf("request %d: %q, X-Cache %q, want %q, %q",
+						i, w.Body.String(), w.Header().Get("X-Cache"), req.wantBody, req.wantCache)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheMiddlewareExpires(t *testing.T) {
+	var calls atomic.Int32
+	h := CacheMiddleware(20*time.Millisecond, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, calls.Add(1))
+	}))
+	get := func() string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Body.String()
+	}
+
+	if a, b := get(), get(); a != "1" || b != "1" {
+		t.Fatalf("got %s, %s, want the cached 1 twice", a, b)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := get(); got != "2" {
+		t.Errorf("after the ttl got %s, want a fresh 2", got)
+	}
+}
+
+// Requests arriving while the first one runs wait for it instead of calling the handler themselves.
+func TestCacheMiddlewareCoalesces(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := CacheMiddleware(time.Minute, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte("slow answer"))
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	bodies := make(chan string, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := http.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer res.Body.Close()
+			b, _ := io.ReadAll(res.Body)
+			bodies <- string(b)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // let them all line up behind the first one
+	close(release)
+	wg.Wait()
+	close(bodies)
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want once", got)
+	}
+	for b := range bodies {
+		if b != "slow answer" {
+			t.Errorf("body = %q", b)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Response compression.
+
+The client lists the encodings it understands in Accept-Encoding, optionally weighted with a "quality" value:
+	Accept-Encoding: gzip;q=1.0, identity;q=0.5, *;q=0
+- q ranges from 0 to 1 (default 1), q=0 means "not acceptable", so "gzip;q=0" means never gzip.
+- "*" stands for any encoding not listed explicitly.
+- "identity" (no encoding) is acceptable unless ruled out explicitly.
+
+AcceptEncodingMiddleware parses the header once and stores the decision in the request context,
+CompressMiddleware (and anything else that cares) just reads it back with NegotiatedEncoding.
+gzip is always there, Brotli ("br", usually ~15-20% smaller on text) only in builds with -tags brotli (see brotli.go).
+
+The other direction exists too, a client can send a gzipped body with "Content-Encoding: gzip",
+DecompressRequestMiddleware undoes that before the handler sees it.
+*/
+
+package main
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// supportedEncodings in order of preference, used to break ties between equal q values.
+var supportedEncodings = []string{"gzip", "identity"}
+
+// compressors build the writer for each encoding CompressMiddleware can produce.
+var compressors = map[string]func(io.Writer) io.WriteCloser{
+	"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+}
+
+func AcceptEncodingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := negotiateEncoding(r.Header.Get("Accept-Encoding"), supportedEncodings)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), encodingKey, enc)))
+	})
+}
+
+// NegotiatedEncoding returns the encoding chosen for r's response,
+// parsing Accept-Encoding itself if AcceptEncodingMiddleware didn't run.
+func NegotiatedEncoding(r *http.Request) string {
+	if enc, ok := r.Context().Value(encodingKey).(string); ok {
+		return enc
+	}
+	return negotiateEncoding(r.Header.Get("Accept-Encoding"), supportedEncodings)
+}
+
+// negotiateEncoding picks the supported encoding with the highest q value.
+// It falls back to identity when nothing better is acceptable.
+func negotiateEncoding(header string, supported []string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0 // -1 means "*" wasn't mentioned
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || f < 0 || f > 1 {
+					f = 0 // a q value we can't make sense of is safest treated as "not acceptable"
+				}
+				q = f
+			}
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "identity", 0.0
+	for _, enc := range supported {
+		q, ok := weights[enc]
+		switch {
+		case ok:
+		case wildcard >= 0:
+			q = wildcard
+		case enc == "identity":
+			q = 0.001 // [1]
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// CompressMiddleware compresses the response body with the encoding negotiated for the request, if any.
+// Responses without a body (HEAD, 204, 304) are left alone, with no Content-Encoding.
+func CompressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding") // [2]
+		enc := NegotiatedEncoding(r)
+		newWriter, ok := compressors[enc]
+		if !ok || r.Method == http.MethodHead { // identity, or a body that's never sent
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, enc: enc, newWriter: newWriter}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+type compressResponseWriter struct {
+	http.ResponseWriter
+	enc         string
+	newWriter   func(io.Writer) io.WriteCloser
+	cw          io.WriteCloser // created on the first Write, so empty responses stay empty
+	wroteHeader bool
+	bodyless    bool // a 204 or 304, nothing to compress
+}
+
+func (c *compressResponseWriter) WriteHeader(code int) {
+	if c.wroteHeader || code < 200 { // 1xx are interim responses, the real one is still to come
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+	c.wroteHeader = true
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		c.bodyless = true
+	} else {
+		c.Header().Set("Content-Encoding", c.enc)
+		c.Header().Del("Content-Length") // [3]
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *compressResponseWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		if c.Header().Get("Content-Type") == "" {
+			c.Header().Set("Content-Type", http.DetectContentType(b)) // [4]
+		}
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.bodyless {
+		return c.ResponseWriter.Write(b) // net/http turns it down with ErrBodyNotAllowed
+	}
+	if c.cw == nil {
+		c.cw = c.newWriter(c.ResponseWriter)
+	}
+	return c.cw.Write(b)
+}
+
+// Flush pushes out what the compressor holds back before flushing the wrapped writer,
+// otherwise a streaming handler's last bytes would sit in the compressor until it returns. [6]
+func (c *compressResponseWriter) Flush() {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if !c.bodyless {
+		if c.cw == nil { // the headers going out say compressed, so the body has to be, even if empty
+			c.cw = c.newWriter(c.ResponseWriter)
+		}
+		if f, ok := c.cw.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	}
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+func (c *compressResponseWriter) Close() error {
+	if c.cw == nil {
+		return nil
+	}
+	return c.cw.Close()
+}
+
+func (c *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// DecompressRequestMiddleware transparently un-gzips request bodies sent with "Content-Encoding: gzip",
+// so handlers and json.Decoder read plain bytes. A body that isn't gzip at all gets a 400,
+// corruption further in fails the handler's reads with a 400 statusError it can pass to writeError. [5]
+func DecompressRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); enc {
+		case "", "identity":
+		case "gzip":
+			gz, err := gzip.NewReader(r.Body) // reads and checks the gzip header right away
+			if err != nil {
+				http.Error(w, "malformed gzip request body", http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+
+			r.Body = readCloser{gzipBody{gz}, r.Body}
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1 // the declared length was the compressed size
+		default:
+			http.Error(w, "unsupported Content-Encoding "+strconv.Quote(enc), http.StatusUnsupportedMediaType)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// gzipBody turns the errors of a corrupt or truncated gzip stream into 400s, they're the client's fault.
+// Anything else, like the connection dropping, is passed on as is.
+type gzipBody struct{ gz *gzip.Reader }
+
+func (b gzipBody) Read(p []byte) (int, error) {
+	n, err := b.gz.Read(p)
+	var corrupt flate.CorruptInputError
+	if errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &corrupt) {
+		err = badRequest(fmt.Errorf("malformed gzip request body: %w", err))
+	}
+	return n, err
+}
+
+/*
+[1] : An unlisted identity is acceptable but should lose against anything the client asked for explicitly,
+			even "gzip;q=0.1".
+
+[2] : Same idea as "Vary: Origin" for CORS, caches must not hand a gzipped response to a client that didn't ask for it.
+
+[3] : A Content-Length set by the handler is the uncompressed size, which would be a lie once we compress.
+			Without it Go falls back to chunked encoding.
+
+[4] : Go sniffs the Content-Type from the first bytes it's given, which would be compressed bytes by now.
+
+[5] : A few kb of gzip can expand to gigabytes (a "zip bomb"), so a handler reading the whole body
+			should still cap it with http.MaxBytesReader, which now counts decompressed bytes.
+
+[6] : http.NewResponseController(w).Flush() finds this Flush before Unwrap. Both gzip's and brotli's writers
+			keep a compressed block open until it's full, Flush ends it early, at some cost in compression ratio.
+*/
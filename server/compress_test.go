@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(s))
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestDecompressRequestMiddleware(t *testing.T) {
+	body := strings.Repeat("hello gzip ", 100)
+	valid := gzipped(body)
+
+	corrupt := bytes.Clone(valid)
+	corrupt[len(corrupt)/2] ^= 0xff // somewhere in the deflate data
+	badChecksum := bytes.Clone(valid)
+	badChecksum[len(badChecksum)-8] ^= 0xff // the CRC-32 in the trailer
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		wantStatus int
+		wantBody   string
+	}{
+		{"plain", "", []byte("plain"), 200, "plain"},
+		{"gzip", "gzip", valid, 200, body},
+		{"gzip any case", " GZip ", valid, 200, body},
+		{"not gzip", "gzip", []byte("plain"), 400, ""},
+		{"corrupt mid-stream", "gzip", corrupt, 400, ""},
+		{"bad checksum", "gzip", badChecksum, 400, ""},
+		{"truncated", "gzip", valid[:len(valid)-10], 400, ""},
+		{"unsupported", "br", valid, 415, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := DecompressRequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					writeError(w, r, err)
+					return
+				}
+				if r.Header.Get("Content-Encoding") != "" {
+					t.Error("Content-Encoding still set for the handler")
+				}
+				w.Write(b)
+			}))
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("handler read %q, want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{"gzip", "identity"}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "identity"},
+		{"gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"gzip;q=0", "identity"},
+		{"gzip; q=0, identity", "identity"},
+		{"gzip;q=0.5, identity;q=0.8", "identity"},
+		{"gzip;q=0.8, identity;q=0.5", "gzip"},
+		{"gzip;q=0.1", "gzip"}, // an unlisted identity loses to anything asked for
+		{"deflate, br", "identity"},
+		{"*", "gzip"},
+		{"*;q=0", "identity"},
+		{"gzip;q=2", "identity"}, // out of range, treated as not acceptable
+		{"gzip;q=abc", "identity"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, supported); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	body := strings.Repeat("compress me ", 100)
+	h := AcceptEncodingMiddleware(CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case "/nothing":
+			return
+		}
+		w.Header().Set("Content-Length", "1200") // the uncompressed size
+		w.Write([]byte(body))
+	})))
+
+	tests := []struct {
+		name, method, path, accept string
+		wantEncoding               string
+		wantEmpty                  bool
+	}{
+		{"gzip", "GET", "/", "gzip, deflate", "gzip", false},
+		{"gzip refused", "GET", "/", "gzip;q=0", "", false},
+		{"no header", "GET", "/", "", "", false},
+		{"204 stays empty", "GET", "/empty", "gzip", "", true},
+		{"304 stays empty", "GET", "/not-modified", "gzip", "", true},
+		{"nothing written", "GET", "/nothing", "gzip", "", true},
+		{"HEAD", "HEAD", "/", "gzip", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+			}
+			if tt.wantEmpty {
+				if rec.Body.Len() != 0 {
+					t.Errorf("%d with a %d byte body", rec.Code, rec.Body.Len())
+				}
+				return
+			}
+
+			got := rec.Body.String()
+			if tt.wantEncoding == "gzip" {
+				if rec.Header().Get("Content-Length") != "" {
+					t.Error("the uncompressed Content-Length was kept")
+				}
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, _ := io.ReadAll(gz)
+				got = string(b)
+			}
+			if got != body {
+				t.Errorf("body = %q", got)
+			}
+		})
+	}
+}
+
+// A flush through http.ResponseController reaches the client as bytes it can already decompress.
+func TestCompressMiddlewareFlush(t *testing.T) {
+	read := make(chan struct{})
+	srv := httptest.NewServer(CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("first "))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+		select { // the rest only once the client got the first part
+		case <-read:
+		case <-time.After(2 * time.Second):
+			t.Error("the client didn't get the flushed part while the handler was still running")
+		}
+		w.Write([]byte("second"))
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip") // set by hand, so the transport doesn't decompress behind our back
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", res.Header.Get("Content-Encoding"))
+	}
+
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make([]byte, len("first "))
+	if _, err := io.ReadFull(gz, first); err != nil || string(first) != "first " {
+		t.Fatalf("before the handler returned, read %q, %v, want \"first \"", first, err)
+	}
+	close(read)
+	if rest, _ := io.ReadAll(gz); string(rest) != "second" {
+		t.Errorf("rest = %q, want \"second\"", rest)
+	}
+}
+
+// compressedBody is what compressedResponse's handler sends, before compression.
+var compressedBody = strings.Repeat("compress me ", 100)
+
+// compressedResponse runs a request asking for accept through the compression middlewares.
+func compressedResponse(accept string) *httptest.ResponseRecorder {
+	h := AcceptEncodingMiddleware(CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(compressedBody))
+	})))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", accept)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// CheckNotModified handles If-Modified-Since for handlers that know when their resource last changed.
+// It always sets Last-Modified, and when the client's copy is still current it writes a 304
+// and returns true, in which case the handler must not write anything else. [1]
+func CheckNotModified(w http.ResponseWriter, r *http.Request, lastMod time.Time) bool {
+	if lastMod.IsZero() {
+		return false
+	}
+	lastMod = lastMod.UTC().Truncate(time.Second) // [2]
+	w.Header().Set("Last-Modified", lastMod.Format(http.TimeFormat))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("If-None-Match") != "" {
+		return false // [3]
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastMod.After(since) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+/*
+[1] : http.ServeContent does all of this for static content, CheckNotModified is for handlers
+			that render their response and only know its modification time.
+
+[2] : HTTP dates have a one second resolution, without truncating a resource modified at 10:00:00.5
+			would always look newer than the "10:00:00" the client sends back.
+
+[3] : When both are sent, If-None-Match (ETags) wins and If-Modified-Since must be ignored (RFC 9110).
+*/
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	lastMod := time.Date(2024, 3, 1, 10, 0, 0, 500_000_000, time.UTC) // half a second past, truncated away
+	stamp := lastMod.Format(http.TimeFormat)
+
+	tests := []struct {
+		name            string
+		method          string
+		ifModifiedSince string
+		ifNoneMatch     string
+		lastMod         time.Time
+		want            bool
+		wantLastMod     string
+	}{
+		{"no header", "GET", "", "", lastMod, false, stamp},
+		{"same time", "GET", stamp, "", lastMod, true, stamp},
+		{"client copy newer", "GET", lastMod.Add(time.Hour).Format(http.TimeFormat), "", lastMod, true, stamp},
+		{"changed since", "GET", lastMod.Add(-time.Hour).Format(http.TimeFormat), "", lastMod, false, stamp},
+		{"head", "HEAD", stamp, "", lastMod, true, stamp},
+		{"post", "POST", stamp, "", lastMod, false, stamp},
+		{"if-none-match wins", "GET", stamp, `"v1"`, lastMod, false, stamp},
+		{"garbage date", "GET", "yesterday", "", lastMod, false, stamp},
+		{"unknown modification time", "GET", stamp, "", time.Time{}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if CheckNotModified(w, r, tt.lastMod) {
+					return
+				}
+				w.Write([]byte("fresh"))
+			})
+			r := httptest.NewRequest(tt.method, "/", nil)
+			if tt.ifModifiedSince != "" {
+				r.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			wantStatus, wantBody := http.StatusOK, "fresh"
+			if tt.want {
+				wantStatus, wantBody = http.StatusNotModified, ""
+			}
+			if w.Code != wantStatus || w.Body.String() != wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), wantStatus, wantBody)
+			}
+			if got := w.Header().Get("Last-Modified"); got != tt.wantLastMod {
+				t.Errorf("Last-Modified = %q, want %q", got, tt.wantLastMod)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+// ctxKey is unexported so no other package can collide with the values we store in a request context.
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	encodingKey
+	geoKey
+	csrfKey
+	principalKey
+	timingKey
+	routeKey
+	botKey
+	originalPathKey
+)
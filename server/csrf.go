@@ -0,0 +1,78 @@
+/*
+CSRF protection with a double-submit cookie.
+
+A malicious page can make the browser POST a form to us, and the browser helpfully attaches our cookies.
+What it can't do is read our cookies. So we hand out a random token in a cookie, and every unsafe request
+must repeat it in an X-CSRF-Token header or a csrf_token form field: only pages served from our origin
+know the value to submit.
+
+Templates put the token in their forms with CSRFToken:
+	<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+const (
+	csrfCookie = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+)
+
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := ""
+		if c, err := r.Cookie(csrfCookie); err == nil && c.Value != "" {
+			token = c.Value
+		} else {
+			token = newCSRFToken()
+			http.SetCookie(w, &http.Cookie{
+				Name:     csrfCookie,
+				Value:    token,
+				Path:     "/",
+				SameSite: http.SameSiteLaxMode,
+				Secure:   r.TLS != nil,
+				// not HttpOnly, the page's own JS has to be able to read it to send the header
+			})
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace: // safe methods [1]
+		default:
+			submitted := r.Header.Get(csrfHeader)
+			if submitted == "" {
+				submitted = r.PostFormValue(csrfField)
+			}
+			if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfKey, token)))
+	})
+}
+
+// CSRFToken is the token to embed in forms rendered for this request.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfKey).(string)
+	return token
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+/*
+[1] : This only holds as long as GET handlers really don't change anything. A GET that deletes a post
+			can be triggered by any <img src="..."> on any site, token or not.
+*/
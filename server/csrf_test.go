@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	const token = "the-token"
+	tests := []struct {
+		name      string
+		method    string
+		cookie    string // "" for a first visit without the cookie
+		header    string
+		form      string // csrf_token form field
+		wantCode  int
+		wantToken string // what the handler sees through CSRFToken, "" for a fresh one
+	}{
+		{"get without cookie", "GET", "", "", "", http.StatusOK, ""},
+		{"get with cookie", "GET", token, "", "", http.StatusOK, token},
+		{"head is safe", "HEAD", token, "", "", http.StatusOK, token},
+		{"post with header", "POST", token, token, "", http.StatusOK, token},
+		{"post with form field", "POST", token, "", token, http.StatusOK, token},
+		{"delete with header", "DELETE", token, token, "", http.StatusOK, token},
+		{"post missing token", "POST", token, "", "", http.StatusForbidden, ""},
+		{"post mismatched header", "POST", token, "other", "", http.StatusForbidden, ""},
+		{"post mismatched form field", "POST", token, "", "other", http.StatusForbidden, ""},
+		{"post without cookie", "POST", "", token, "", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := CSRFMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = CSRFToken(r.Context())
+			}))
+
+			var r *http.Request
+			if tt.form != "" {
+				r = httptest.NewRequest(tt.method, "/posts", strings.NewReader(url.Values{csrfField: {tt.form}}.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				r = httptest.NewRequest(tt.method, "/posts", nil)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: csrfCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set(csrfHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			var set *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == csrfCookie {
+					set = c
+				}
+			}
+			if (set != nil) != (tt.cookie == "") {
+				t.Errorf("Set-Cookie = %v, want a new cookie only when the request had none", set)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			want := tt.wantToken
+			if want == "" {
+				want = set.Value
+			}
+			if seen == "" || seen != want {
+				t.Errorf("CSRFToken = %q, want %q", seen, want)
+			}
+		})
+	}
+}
+
+func TestNewCSRFToken(t *testing.T) {
+	a, b := newCSRFToken(), newCSRFToken()
+	if len(a) < 40 || a == b {
+		t.Errorf("tokens %q and %q, want two different 32 byte tokens", a, b)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Degraded mode, a manual load shedding switch.
+
+	kill -USR1 <pid>   # toggle
+
+While degraded, every response carries "X-Degraded: true", and the endpoints wrapped with Expensive
+stop doing their work: they serve the last good response they produced for that URL and user, or a 503 if they have none yet.
+The flag is an atomic.Bool, read by every request and written by the signal goroutine, no locks involved.
+*/
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+type DegradedMode struct {
+	Logger logger.Logger // told when the mode flips, nil means logger.Default()
+
+	on atomic.Bool
+}
+
+func (d *DegradedMode) Enabled() bool { return d.on.Load() }
+
+// Toggle flips the mode and returns the new state.
+func (d *DegradedMode) Toggle() bool {
+	for {
+		old := d.on.Load()
+		if d.on.CompareAndSwap(old, !old) { // [1]
+			logger.Or(d.Logger).Info("degraded mode toggled", "on", !old)
+			return !old
+		}
+	}
+}
+
+func (d *DegradedMode) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Enabled() {
+			w.Header().Set("X-Degraded", "true")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type staleResponse struct {
+	header http.Header
+	body   []byte
+}
+
+// maxStaleResponses bounds what Expensive keeps per handler, a crawler walking through ?page=1,2,3...
+// shouldn't be able to fill the memory with pages we'd only ever serve while degraded.
+const maxStaleResponses = 1000
+
+// Expensive marks next as sheddable, see the top of the file.
+// Stale responses are kept per user and per DefaultCacheKey (method, path and query), so while degraded
+// every URL gets its own last good answer, and nobody gets another user's. [2]
+func (d *DegradedMode) Expensive(next http.Handler) http.Handler {
+	var mu sync.RWMutex
+	last := make(map[string]*staleResponse)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := Principal(r.Context()) + " " + DefaultCacheKey(r)
+
+		if !d.Enabled() {
+			rec := &teeRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status == http.StatusOK {
+				mu.Lock()
+				if _, ok := last[key]; ok || len(last) < maxStaleResponses {
+					last[key] = &staleResponse{header: rec.header, body: rec.body.Bytes()}
+				}
+				mu.Unlock()
+			}
+			return
+		}
+
+		mu.RLock()
+		stale := last[key]
+		mu.RUnlock()
+		if stale == nil {
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		for k, v := range stale.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+		w.Write(stale.body)
+	})
+}
+
+/*
+[1] : CompareAndSwap only stores the new value if the flag still holds the one we read,
+			so two signals arriving at once can't both read false and both store true.
+
+[2] : A single "last response" for the whole handler would answer every URL it serves with whichever page
+			happened to be rendered last, /user/2 with /user/1's profile. Same rule as for CacheMiddleware's key.
+*/
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// ToggleOnSignal is a no-op where SIGUSR1 doesn't exist, d can still be toggled from code.
+func (d *DegradedMode) ToggleOnSignal() {}
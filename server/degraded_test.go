@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDegradedModeExpensive(t *testing.T) {
+	d := &DegradedMode{}
+	h := PrincipalFromAPIKeyMiddleware(APIKeys)(d.Middleware(d.Expensive(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fresh " + r.URL.RequestURI() + " for " + Principal(r.Context())))
+	}))))
+
+	get := func(target, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", target, nil)
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	get("/user/1", "")
+	get("/user/2?tab=posts", "")
+	get("/me", "demo-key-amit")
+
+	if !d.Toggle() {
+		t.Fatal("Toggle didn't turn degraded mode on")
+	}
+
+	tests := []struct {
+		target, apiKey string
+		wantStatus     int
+		wantBody       string
+	}{
+		{"/user/1", "", http.StatusOK, "fresh /user/1 for "},
+		{"/user/2?tab=posts", "", http.StatusOK, "fresh /user/2?tab=posts for "},
+		{"/user/2", "", http.StatusServiceUnavailable, ""},
+		{"/user/3", "", http.StatusServiceUnavailable, ""},
+		{"/me", "demo-key-amit", http.StatusOK, "fresh /me for amit"},
+		{"/me", "demo-key-guest", http.StatusServiceUnavailable, ""},
+		{"/me", "", http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		w := get(tt.target, tt.apiKey)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s as %q: status %d, want %d", tt.target, tt.apiKey, w.Code, tt.wantStatus)
+			continue
+		}
+		if w.Header().Get("X-Degraded") != "true" {
+			t.Errorf("%s: missing X-Degraded", tt.target)
+		}
+		if tt.wantStatus == http.StatusOK {
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("%s as %q: body %q, want %q", tt.target, tt.apiKey, w.Body.String(), tt.wantBody)
+			}
+			if w.Header().Get("Warning") == "" {
+				t.Errorf("%s: stale response without a Warning header", tt.target)
+			}
+		}
+	}
+
+	if d.Toggle() {
+		t.Fatal("second Toggle didn't turn degraded mode off")
+	}
+	if w := get("/user/3", ""); w.Code != http.StatusOK || w.Header().Get("X-Degraded") != "" {
+		t.Errorf("after recovering: %d, X-Degraded %q", w.Code, w.Header().Get("X-Degraded"))
+	}
+}
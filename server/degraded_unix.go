@@ -0,0 +1,20 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ToggleOnSignal flips d every time the process receives SIGUSR1.
+func (d *DegradedMode) ToggleOnSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	go func() {
+		for range ch {
+			d.Toggle()
+		}
+	}()
+}
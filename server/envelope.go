@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// envelope is the one shape every JSON endpoint answers with, success or not:
+//
+//	{"data": {...}, "meta": {...}, "error": null}
+//	{"data": null, "meta": null, "error": "user not found"}
+//
+// Clients check "error" first and never have to guess how a given endpoint reports failures.
+type envelope struct {
+	Data  any     `json:"data"`
+	Meta  any     `json:"meta"`
+	Error *string `json:"error"` // a pointer so that success encodes as null rather than ""
+}
+
+func WriteEnvelope(w http.ResponseWriter, status int, data any, meta any) error {
+	return writeJSON(w, status, envelope{Data: data, Meta: meta})
+}
+
+func WriteErrorEnvelope(w http.ResponseWriter, status int, message string) error {
+	return writeJSON(w, status, envelope{Error: &message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	body, err := json.Marshal(v) // marshal first, once WriteHeader is called it's too late to report a 500
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteEnvelope(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      func(w http.ResponseWriter) error
+		wantStatus int
+		wantBody   string
+		wantErr    bool
+	}{
+		{"data", func(w http.ResponseWriter) error {
+			return WriteEnvelope(w, http.StatusOK, map[string]string{"name": "Amit"}, nil)
+		}, http.StatusOK, `{"data":{"name":"Amit"},"meta":null,"error":null}`, false},
+		{"data and meta", func(w http.ResponseWriter) error {
+			return WriteEnvelope(w, http.StatusCreated, []int{1, 2}, map[string]int{"total": 2})
+		}, http.StatusCreated, `{"data":[1,2],"meta":{"total":2},"error":null}`, false},
+		{"error", func(w http.ResponseWriter) error {
+			return WriteErrorEnvelope(w, http.StatusNotFound, "user not found")
+		}, http.StatusNotFound, `{"data":null,"meta":null,"error":"user not found"}`, false},
+		{"unencodable data", func(w http.ResponseWriter) error {
+			return WriteEnvelope(w, http.StatusOK, make(chan int), nil)
+		}, http.StatusInternalServerError, "Internal Server Error", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := tt.write(w)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want an error: %v", err, tt.wantErr)
+			}
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody+"\n" {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody+"\n")
+			}
+			if !tt.wantErr && w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestUserEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	user(w, httptest.NewRequest("GET", "/user", nil))
+	if want := `{"data":{"name":"Amit"},"meta":null,"error":null}` + "\n"; w.Body.String() != want {
+		t.Errorf("/user = %q, want %q", w.Body.String(), want)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// statusError is an error that knows which HTTP status it should be reported with.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return &statusError{status: http.StatusBadRequest, err: err}
+}
+
+type errorResponse struct {
+	Error     string       `json:"error"`
+	Fields    []FieldError `json:"fields,omitempty"` // per field details, for validation errors
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// writeError is the one place handlers report errors from.
+// Errors without a status are treated as 500s and their message is logged instead of shown to the client.
+// The body and the X-Request-ID header carry the request id, so a client reporting a failure
+// gives us what we need to find the matching log line. [1]
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := http.StatusInternalServerError, "Internal Server Error"
+	var fields []FieldError
+
+	var se *statusError
+	var ve ValidationErrors
+	switch {
+	case errors.As(err, &ve):
+		status, msg, fields = http.StatusUnprocessableEntity, "validation failed", ve
+	case errors.As(err, &se):
+		status, msg = se.status, se.Error()
+	default:
+		logger.FromContext(r.Context()).Error("request failed", "request_id", RequestID(r.Context()), "err", err)
+	}
+
+	id := RequestID(r.Context())
+	if id != "" {
+		w.Header().Set("X-Request-ID", id)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg, Fields: fields, RequestID: id})
+}
+
+// MethodNotAllowed answers a 405 listing the methods the route does support in the Allow header.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, r, &statusError{
+		status: http.StatusMethodNotAllowed,
+		err:    fmt.Errorf("method %s not allowed", r.Method),
+	})
+}
+
+// FieldError is what's wrong with one field of a request.
+// Code is stable for clients to switch on ("required", "type", "max"...), Message is for humans.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every FieldError of a request, writeError answers it with a 422 listing them.
+// Validators return it instead of stopping at the first problem, so a client can fix everything in one go.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, fe := range v {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, ", ")
+}
+
+/*
+[1] : The id only exists if RequestIDMiddleware ran before the handler, that's why it wraps everything else in main.
+*/
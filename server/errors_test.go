@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		requestID  string // sent by the client, empty lets RequestIDMiddleware make one up
+		wantStatus int
+		wantMsg    string
+		wantLogged bool // internal errors are logged instead of shown
+	}{
+		{"internal", errors.New("db password is hunter2"), "req-1", http.StatusInternalServerError, "Internal Server Error", true},
+		{"bad request", badRequest(errors.New("name is required")), "req-2", http.StatusBadRequest, "name is required", false},
+		{"wrapped status", fmt.Errorf("creating post: %w", &statusError{status: http.StatusConflict, err: errors.New("already exists")}),
+			"req-3", http.StatusConflict, "already exists", false},
+		{"generated id", badRequest(errors.New("nope")), "", http.StatusBadRequest, "nope", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			h := RequestIDMiddleware(LoggerMiddleware(logger.New(&logs, slog.LevelInfo))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { writeError(w, r, tt.err) })))
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.requestID != "" {
+				r.Header.Set("X-Request-ID", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus || w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("got %d %q, want %d application/json", w.Code, w.Header().Get("Content-Type"), tt.wantStatus)
+			}
+			var body errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q: %v", w.Body.String(), err)
+			}
+			if body.Error != tt.wantMsg {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantMsg)
+			}
+
+			header := w.Header().Get("X-Request-ID")
+			if header == "" || body.RequestID != header {
+				t.Errorf("request id in the body %q, in the header %q, want the same non empty id", body.RequestID, header)
+			}
+			if tt.requestID != "" && header != tt.requestID {
+				t.Errorf("request id = %q, want the client's %q", header, tt.requestID)
+			}
+
+			logged := strings.Contains(logs.String(), "request failed")
+			if logged != tt.wantLogged {
+				t.Errorf("logged = %v, want %v:\n%s", logged, tt.wantLogged, logs.String())
+			}
+			if logged && !strings.Contains(logs.String(), "request_id="+header) {
+				t.Errorf("log line doesn't carry the request id %q:\n%s", header, logs.String())
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		method    string
+		wantAllow string
+	}{
+		{"helper", func(w http.ResponseWriter, r *http.Request) { MethodNotAllowed(w, r, "GET", "POST") }, "DELETE", "GET, POST"},
+		{"handleUserById", handleUserById, "POST", "GET"},
+		{"handlePostCreate", handlePostCreate, "GET", "POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest(tt.method, "/", nil))
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("status = %d, want 405", w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			var body errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("not a JSON body (%q): %q, %v", w.Header().Get("Content-Type"), w.Body.String(), err)
+			}
+			if want := "method " + tt.method + " not allowed"; body.Error != want {
+				t.Errorf("error = %q, want %q", body.Error, want)
+			}
+		})
+	}
+}
+
+func TestWriteErrorValidation(t *testing.T) {
+	errs := ValidationErrors{
+		{"title", "required", "is required"},
+		{"stars", "max", "must be at most 5"},
+	}
+	const wantBody = `{"error":"validation failed","fields":[` +
+		`{"field":"title","code":"required","message":"is required"},` +
+		`{"field":"stars","code":"max","message":"must be at most 5"}],"request_id":"req-1"}` + "\n"
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"as is", errs},
+		{"wrapped", fmt.Errorf("creating post: %w", errs)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			h := RequestIDMiddleware(LoggerMiddleware(logger.New(&logs, slog.LevelInfo))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { writeError(w, r, tt.err) })))
+			r := httptest.NewRequest("POST", "/posts/create", nil)
+			r.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want 422", w.Code)
+			}
+			if w.Body.String() != wantBody {
+				t.Errorf("body = %s\nwant   %s", w.Body, wantBody)
+			}
+			if logs.Len() != 0 {
+				t.Errorf("a client error got logged: %s", logs.String())
+			}
+		})
+	}
+
+	if got, want := errs.Error(), "validation failed: title is required, stars must be at most 5"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
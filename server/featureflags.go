@@ -0,0 +1,62 @@
+/*
+Feature flags: ship the code switched off and turn it on for some users first.
+
+A gated route answers 404 while its flag is off, as if it didn't exist, rather than a 403 that would
+advertise there's something there to get at. Flags can be on for everyone or just for named principals,
+the name comes from whichever auth middleware ran first and stored it with WithPrincipal (PrincipalFromAPIKeyMiddleware in auth.go).
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string) bool
+}
+
+// StaticFlags is a FlagProvider fixed at startup.
+type StaticFlags struct {
+	Global   map[string]bool     // flags on for everyone
+	ForUsers map[string][]string // flag -> principals it's on for
+}
+
+func (f StaticFlags) Enabled(ctx context.Context, flag string) bool {
+	if f.Global[flag] {
+		return true
+	}
+	name := Principal(ctx)
+	return name != "" && slices.Contains(f.ForUsers[flag], name)
+}
+
+// FeatureGateMiddleware returns a gate to tag routes with, e.g.
+//
+//	gate := FeatureGateMiddleware(flags)
+//	mux.Handle("GET /posts/drafts", gate("drafts")(handler))
+func FeatureGateMiddleware(flags FlagProvider) func(flag string) func(http.Handler) http.Handler {
+	return func(flag string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !flags.Enabled(r.Context(), flag) {
+					http.NotFound(w, r)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+}
+
+// WithPrincipal stores the authenticated user's name in ctx.
+func WithPrincipal(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, principalKey, name)
+}
+
+// Principal is the name WithPrincipal stored, "" for anonymous requests.
+func Principal(ctx context.Context) string {
+	name, _ := ctx.Value(principalKey).(string)
+	return name
+}
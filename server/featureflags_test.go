@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeatureGateMiddleware(t *testing.T) {
+	gate := FeatureGateMiddleware(StaticFlags{
+		Global:   map[string]bool{"search": true},
+		ForUsers: map[string][]string{"drafts": {"amit"}},
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + Principal(r.Context())))
+	})
+
+	tests := []struct {
+		name   string
+		flag   string
+		apiKey string
+		want   int
+	}{
+		{"global flag, anonymous", "search", "", http.StatusOK},
+		{"user flag, anonymous", "drafts", "", http.StatusNotFound},
+		{"user flag, listed user", "drafts", "demo-key-amit", http.StatusOK},
+		{"user flag, other user", "drafts", "demo-key-guest", http.StatusNotFound},
+		{"user flag, unknown key", "drafts", "made-up", http.StatusNotFound},
+		{"unknown flag", "nope", "demo-key-amit", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := PrincipalFromAPIKeyMiddleware(APIKeys)(gate(tt.flag)(ok))
+			r := httptest.NewRequest("GET", "/posts/drafts", nil)
+			if tt.apiKey != "" {
+				r.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDraftsRouteReachable(t *testing.T) {
+	h := PrincipalFromAPIKeyMiddleware(APIKeys)(newMux(&DegradedMode{}, NewMetrics(), false))
+	r := httptest.NewRequest("GET", "/posts/drafts", nil)
+	r.Header.Set("X-API-Key", "demo-key-amit")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "Your drafts, amit" {
+		t.Fatalf("got %d %q, want 200 for amit", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFieldsMiddleware(t *testing.T) {
+	jsonHandler := func(status int, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		})
+	}
+	text := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a":1,"b":2}`)) // sniffed as text/plain
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		query   string
+		want    string
+	}{
+		{"object", jsonHandler(200, `{"a":1,"b":2,"c":3}`), "?fields=a,c", "{\"a\":1,\"c\":3}\n"},
+		{"array", jsonHandler(200, `[{"a":1,"b":2},{"a":3,"b":4}]`), "?fields=b", "[{\"b\":2},{\"b\":4}]\n"},
+		{"unknown names", jsonHandler(200, `{"a":1}`), "?fields=zzz", "{}\n"},
+		{"large numbers kept exact", jsonHandler(200, `{"id":9007199254740993}`), "?fields=id", "{\"id\":9007199254740993}\n"},
+		{"no fields param", jsonHandler(200, `{"a":1,"b":2}`), "", `{"a":1,"b":2}`},
+		{"error response untouched", jsonHandler(404, `{"error":"x","b":2}`), "?fields=b", `{"error":"x","b":2}`},
+		{"not json", text, "?fields=a", `{"a":1,"b":2}`},
+		{"json scalar", jsonHandler(200, `42`), "?fields=a", `42`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			FieldsMiddleware(tt.handler).ServeHTTP(rec, httptest.NewRequest("GET", "/x"+tt.query, nil))
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The HTML home page and the streaming routes must not go through FieldsMiddleware.
+func TestFieldsOnlyOnJSONRoutes(t *testing.T) {
+	mux := newMux(&DegradedMode{}, NewMetrics(), false)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/routes?fields=pattern", nil))
+	if body := rec.Body.String(); rec.Code != 200 || !strings.Contains(body, `"pattern"`) || strings.Contains(body, `"method"`) {
+		t.Errorf("GET /routes?fields=pattern = %d %s", rec.Code, body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/posts?fields=a", nil))
+	if body := rec.Body.String(); body != "Your posts were here..." {
+		t.Errorf("GET /posts?fields=a = %q", body)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Tagging requests with where they come from.
+
+A real GeoLookup would query a GeoIP database (MaxMind and friends) or an HTTP service,
+StubGeoLookup stands in for one so the demo runs offline.
+The lookup is best effort: if it fails or is slow the request carries on untagged, analytics
+are never worth failing (or stalling) a request over.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+var ErrGeoUnknown = errors.New("geo: no information for this address")
+
+type GeoInfo struct {
+	Country string // ISO 3166 alpha-2 code, e.g. "IN"
+	ASN     int    // autonomous system number of the network the address belongs to
+}
+
+type GeoLookup func(ip string) (GeoInfo, error)
+
+// StubGeoLookup knows about local addresses only, everything else is ErrGeoUnknown.
+func StubGeoLookup(ip string) (GeoInfo, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return GeoInfo{}, ErrGeoUnknown
+	}
+	if addr.IsLoopback() || addr.IsPrivate() {
+		return GeoInfo{Country: "ZZ"}, nil // ZZ is the user-assigned "unknown or unspecified" code
+	}
+	return GeoInfo{}, ErrGeoUnknown
+}
+
+// GeoMiddleware looks up the client address, storing the result in the request context
+// and the country in an X-Geo-Country response header. The lookup gets at most timeout.
+func GeoMiddleware(lookup GeoLookup, timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+			info, err := geoWithTimeout(lookup, ip, timeout)
+			if err != nil {
+				if !errors.Is(err, ErrGeoUnknown) {
+					logger.FromContext(r.Context()).Warn("geo lookup failed", "ip", ip, "err", err)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if info.Country != "" {
+				w.Header().Set("X-Geo-Country", info.Country)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), geoKey, info)))
+		})
+	}
+}
+
+// Geo returns the GeoInfo GeoMiddleware stored in ctx, ok is false if the lookup didn't succeed.
+func Geo(ctx context.Context) (info GeoInfo, ok bool) {
+	info, ok = ctx.Value(geoKey).(GeoInfo)
+	return info, ok
+}
+
+func geoWithTimeout(lookup GeoLookup, ip string, timeout time.Duration) (GeoInfo, error) {
+	type result struct {
+		info GeoInfo
+		err  error
+	}
+	done := make(chan result, 1) // buffered, so a lookup finishing after the timeout doesn't leak its goroutine [1]
+	go func() {
+		info, err := lookup(ip)
+		done <- result{info, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.info, res.err
+	case <-time.After(timeout):
+		return GeoInfo{}, errors.New("geo: lookup timed out")
+	}
+}
+
+/*
+[1] : The goroutine still runs the slow lookup to the end, we just stop waiting for it.
+			With an unbuffered channel it would then block forever on the send, nobody is left to receive.
+*/
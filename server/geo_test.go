@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestGeoMiddleware(t *testing.T) {
+	known := func(ip string) (GeoInfo, error) {
+		if ip == "203.0.113.7" {
+			return GeoInfo{Country: "IN", ASN: 64500}, nil
+		}
+		return GeoInfo{}, ErrGeoUnknown
+	}
+	tests := []struct {
+		name        string
+		lookup      GeoLookup
+		remoteAddr  string
+		wantInfo    GeoInfo
+		wantOK      bool
+		wantWarning bool
+	}{
+		{"found", known, "203.0.113.7:5000", GeoInfo{Country: "IN", ASN: 64500}, true, false},
+		{"unknown", known, "198.51.100.1:5000", GeoInfo{}, false, false},
+		{"stub, loopback", StubGeoLookup, "127.0.0.1:5000", GeoInfo{Country: "ZZ"}, true, false},
+		{"stub, public", StubGeoLookup, "8.8.8.8:5000", GeoInfo{}, false, false},
+		{"failing lookup", func(string) (GeoInfo, error) { return GeoInfo{}, errors.New("db unreachable") },
+			"203.0.113.7:5000", GeoInfo{}, false, true},
+		{"slow lookup", func(string) (GeoInfo, error) { time.Sleep(time.Second); return GeoInfo{Country: "IN"}, nil },
+			"203.0.113.7:5000", GeoInfo{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			var gotInfo GeoInfo
+			var gotOK, served bool
+			h := LoggerMiddleware(logger.New(&logs, slog.LevelInfo))(GeoMiddleware(tt.lookup, 20*time.Millisecond)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					served = true
+					gotInfo, gotOK = Geo(r.Context())
+				})))
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			begin := time.Now()
+			h.ServeHTTP(w, r)
+
+			if !served {
+				t.Fatal("the request didn't reach the handler")
+			}
+			if time.Since(begin) > 500*time.Millisecond {
+				t.Errorf("the request waited %v for the lookup", time.Since(begin))
+			}
+			if gotInfo != tt.wantInfo || gotOK != tt.wantOK {
+				t.Errorf("Geo = %+v, %v, want %+v, %v", gotInfo, gotOK, tt.wantInfo, tt.wantOK)
+			}
+			if got := w.Header().Get("X-Geo-Country"); got != tt.wantInfo.Country {
+				t.Errorf("X-Geo-Country = %q, want %q", got, tt.wantInfo.Country)
+			}
+			if warned := strings.Contains(logs.String(), "geo lookup failed"); warned != tt.wantWarning {
+				t.Errorf("warning logged: %v, want %v\n%s", warned, tt.wantWarning, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Host header validation.
+
+The Host header is whatever the client says it is. Anything that builds absolute URLs from it
+(password reset links, redirects, canonical URLs) can be tricked into pointing at an attacker's domain:
+	Host: evil.example
+So only known hosts are let through. Patterns are either exact ("example.com")
+or a wildcard for any subdomain ("*.example.com" matches "api.example.com", not "example.com" itself),
+matched by internal/match, the same matcher the CORS middleware uses for origins.
+*/
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/amitsuthar69/go-backend/internal/match"
+)
+
+func HostAllowlistMiddleware(allowed []string) func(http.Handler) http.Handler {
+	hosts := make([]string, len(allowed))
+	for i, pattern := range allowed {
+		hosts[i] = canonicalHost(pattern)
+	}
+	m := match.MustCompile(hosts) // [1]
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.Matches(canonicalHost(r.Host)) {
+				http.Error(w, "Invalid Host header", http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// canonicalHost lowercases host and drops the port and the trailing dot of a fully qualified name,
+// so "API.Example.com.:3000" and "api.example.com" compare equal.
+func canonicalHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]") // a bare IPv6 literal without a port
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+/*
+[1] : The patterns are fixed when the server starts, an invalid one is a programming error, hence the panic.
+*/
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostAllowlistMiddleware(t *testing.T) {
+	h := HostAllowlistMiddleware([]string{"example.com", "*.api.example.com", "localhost", "[::1]"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"example.com", http.StatusOK},
+		{"EXAMPLE.com", http.StatusOK},
+		{"example.com.", http.StatusOK},
+		{"localhost:3000", http.StatusOK},
+		{"[::1]:3000", http.StatusOK},
+		{"v1.api.example.com", http.StatusOK},
+		{"a.b.api.example.com", http.StatusOK},
+		{"api.example.com", http.StatusBadRequest}, // the wildcard needs a subdomain
+		{"www.example.com", http.StatusBadRequest},
+		{"evil.example", http.StatusBadRequest},
+		{"evilapi.example.com", http.StatusBadRequest},
+		{"example.com.evil.example", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("Host %q: status %d, want %d", tt.host, w.Code, tt.want)
+			}
+		})
+	}
+}
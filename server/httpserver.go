@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+var ErrNilHandler = errors.New("server: nil handler, pass a mux explicitly instead of relying on http.DefaultServeMux")
+
+// Timeouts are the http.Server timeouts that default to zero, i.e. "wait forever". [2]
+type Timeouts struct {
+	ReadHeader time.Duration // time to receive the request line and headers
+	Read       time.Duration // time to receive the whole request, body included
+	Write      time.Duration // from the end of the request headers until the response is written
+	Idle       time.Duration // how long a keep-alive connection may sit between requests
+}
+
+var DefaultTimeouts = Timeouts{
+	ReadHeader: 5 * time.Second,
+	Read:       15 * time.Second,
+	Write:      30 * time.Second,
+	Idle:       2 * time.Minute,
+}
+
+// ApplyTimeouts sets the non-zero timeouts of t on srv.
+func ApplyTimeouts(srv *http.Server, t Timeouts) {
+	if t.ReadHeader > 0 {
+		srv.ReadHeaderTimeout = t.ReadHeader
+	}
+	if t.Read > 0 {
+		srv.ReadTimeout = t.Read
+	}
+	if t.Write > 0 {
+		srv.WriteTimeout = t.Write
+	}
+	if t.Idle > 0 {
+		srv.IdleTimeout = t.Idle
+	}
+}
+
+// NewServer is http.Server{Addr: addr, Handler: h} minus two footguns:
+// - a nil handler silently means http.DefaultServeMux, which anything imported can register routes on. [1]
+// - the zero timeouts mean "wait forever" for slow clients, so DefaultTimeouts are applied.
+func NewServer(addr string, h http.Handler) (*http.Server, error) {
+	if h == nil {
+		return nil, ErrNilHandler
+	}
+	srv := &http.Server{Addr: addr, Handler: h}
+	ApplyTimeouts(srv, DefaultTimeouts)
+	return srv, nil
+}
+
+/*
+[1] : For instance importing net/http/pprof registers /debug/pprof/ on http.DefaultServeMux as a side effect,
+			exposing profiling endpoints on any server that was started with a nil handler.
+
+[2] : Slowloris: a client opens lots of connections and sends its headers one byte at a time, never finishing.
+			With no ReadHeaderTimeout every one of those connections (and its goroutine) is held open forever,
+			until the server runs out of file descriptors. The attacker barely spends any bandwidth doing it.
+			WriteTimeout also bounds streaming responses (like SSE), raise it or use
+			http.ResponseController.SetWriteDeadline per request for those.
+*/
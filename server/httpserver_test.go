@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.Handler
+		wantErr error
+	}{
+		{"nil handler", nil, ErrNilHandler},
+		{"mux", http.NewServeMux(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := NewServer(":0", tt.handler)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if srv != nil {
+					t.Error("got a server along with the error")
+				}
+				return
+			}
+			if srv.Handler != tt.handler || srv.Addr != ":0" {
+				t.Errorf("got Addr %q, Handler %v", srv.Addr, srv.Handler)
+			}
+			if srv.ReadHeaderTimeout <= 0 || srv.ReadTimeout <= 0 || srv.WriteTimeout <= 0 || srv.IdleTimeout <= 0 {
+				t.Errorf("some timeouts are zero: header %v, read %v, write %v, idle %v",
+					srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+			}
+		})
+	}
+}
+
+func TestApplyTimeouts(t *testing.T) {
+	tests := []struct {
+		name string
+		t    Timeouts
+		want Timeouts
+	}{
+		{"all set", Timeouts{1, 2, 3, 4}, Timeouts{1, 2, 3, 4}},
+		{"zero keeps what's there", Timeouts{ReadHeader: 1}, Timeouts{1, 20, 30, 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &http.Server{ReadHeaderTimeout: 10, ReadTimeout: 20, WriteTimeout: 30, IdleTimeout: 40}
+			ApplyTimeouts(srv, tt.t)
+			got := Timeouts{srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHeaderTimeoutDropsSlowloris(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a request that never finished its headers reached the handler")
+	}))
+	ApplyTimeouts(ts.Config, Timeouts{ReadHeader: 100 * time.Millisecond})
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second)) // the test's own safety net
+	begin := time.Now()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nX-Slow: ")) // and then nothing
+
+	_, err = io.ReadAll(conn) // returns once the server hangs up
+	elapsed := time.Since(begin)
+	if err != nil {
+		t.Fatalf("the server didn't close the connection: %v", err)
+	}
+	if elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("connection dropped after %v, want about the 100ms ReadHeaderTimeout", elapsed)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Idempotency keys.
+
+A client that times out on a POST can't tell whether the server processed it, so retrying might
+create the same post twice. With an "Idempotency-Key" header (a random value the client picks per operation)
+the server can recognise the retry and answer with the response it already produced instead of running the handler again.
+- Keys are scoped to the client (its principal, or its IP when anonymous) and to method + path, and forgotten after ttl.
+- A retry arriving while the first request is still running waits for it instead of running in parallel.
+- 5xx responses aren't remembered, the operation failed so a retry should really try again.
+  Neither is a handler that panicked, even though it never got to write a 500 itself.
+*/
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type idempotentResponse struct {
+	done    chan struct{} // closed once the fields below are filled in
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+type idempotencyCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotentResponse
+}
+
+func IdempotencyMiddleware(ttl time.Duration) func(http.Handler) http.Handler {
+	c := &idempotencyCache{ttl: ttl, entries: make(map[string]*idempotentResponse)}
+	return c.middleware
+}
+
+// lookup returns the entry for key, or registers a new in-flight one (owner == true) if there's none.
+func (c *idempotencyCache) lookup(key string) (entry *idempotentResponse, owner bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range c.entries { // lazy cleanup, there's no background goroutine to stop
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if e, ok := c.entries[key]; ok {
+		return e, false
+	}
+	e := &idempotentResponse{done: make(chan struct{})}
+	c.entries[key] = e
+	return e, true
+}
+
+func (c *idempotencyCache) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key = idempotencyClient(r) + " " + r.Method + " " + r.URL.Path + " " + key // [2]
+
+		entry, owner := c.lookup(key)
+		if !owner {
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+			if entry.status == 0 { // the first attempt failed and was forgotten, let this one run
+				c.middleware(next).ServeHTTP(w, r)
+				return
+			}
+			for k, v := range entry.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		}
+
+		c.run(next, w, r, key, entry)
+	})
+}
+
+func (c *idempotencyCache) run(next http.Handler, w http.ResponseWriter, r *http.Request, key string, entry *idempotentResponse) {
+	rec := &teeRecorder{ResponseWriter: w}
+	defer func() {
+		p := recover()
+		c.mu.Lock()
+		switch {
+		case p != nil:
+			rec.status = http.StatusInternalServerError // forgotten below, RecoverMiddleware answers this one
+		case rec.status == 0:
+			rec.status = http.StatusOK
+		}
+		if rec.status >= 500 {
+			delete(c.entries, key)
+		} else {
+			entry.status = rec.status
+			entry.header = rec.header
+			entry.body = rec.body.Bytes()
+			entry.expires = time.Now().Add(c.ttl)
+		}
+		c.mu.Unlock()
+		close(entry.done) // [1]
+		if p != nil {
+			panic(p)
+		}
+	}()
+	next.ServeHTTP(rec, r)
+}
+
+// idempotencyClient is who the key belongs to.
+func idempotencyClient(r *http.Request) string {
+	if name := Principal(r.Context()); name != "" {
+		return "user:" + name
+	}
+	return "ip:" + clientIP(r)
+}
+
+// teeRecorder passes the response through to the client while keeping a copy of it.
+type teeRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (t *teeRecorder) WriteHeader(code int) {
+	if t.status == 0 {
+		t.status = code
+		t.header = t.Header().Clone() // headers are frozen once sent, snapshot them now
+	}
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *teeRecorder) Write(b []byte) (int, error) {
+	if t.status == 0 {
+		t.WriteHeader(http.StatusOK)
+	}
+	t.body.Write(b)
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *teeRecorder) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+/*
+[1] : Closing a channel wakes up every goroutine receiving from it, which is how all the waiting retries
+			get released at once. The entry is filled in before the close, so they all see the final response.
+
+[2] : Idempotency keys are random but not secret, they travel in logs and proxies like any header.
+			Scoped to method and path alone, a client that learned or guessed another's key would be handed
+			the other client's response.
+*/
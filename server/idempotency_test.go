@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	created := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("post " + strconv.Itoa(int(n))))
+	})
+	h := PrincipalFromAPIKeyMiddleware(APIKeys)(IdempotencyMiddleware(time.Hour)(created))
+
+	send := func(key, apiKey, remote string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/posts/create", nil)
+		r.RemoteAddr = remote
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name, key, apiKey, remote string
+		wantBody                  string
+		wantReplayed              bool
+	}{
+		{"first request runs", "k1", "", "1.1.1.1:1", "post 1", false},
+		{"retry is replayed", "k1", "", "1.1.1.1:1", "post 1", true},
+		{"no key always runs", "", "", "1.1.1.1:1", "post 2", false},
+		{"same key from another ip runs", "k1", "", "2.2.2.2:1", "post 3", false},
+		{"same key from a user runs", "k1", "demo-key-amit", "1.1.1.1:1", "post 4", false},
+		{"the user's retry from elsewhere is replayed", "k1", "demo-key-amit", "3.3.3.3:1", "post 4", true},
+	}
+	for _, tt := range tests {
+		w := send(tt.key, tt.apiKey, tt.remote)
+		if w.Code != http.StatusCreated || w.Body.String() != tt.wantBody {
+			t.Errorf("%s: got %d %q, want 201 %q", tt.name, w.Code, w.Body.String(), tt.wantBody)
+		}
+		if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplayed {
+			t.Errorf("%s: replayed = %v, want %v", tt.name, replayed, tt.wantReplayed)
+		}
+	}
+}
+
+func TestIdempotencyMiddlewareForgetsPanics(t *testing.T) {
+	var calls atomic.Int32
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			panic("database on fire")
+		}
+		w.Write([]byte("ok"))
+	})
+	h := RecoverMiddleware(nil)(IdempotencyMiddleware(time.Hour)(flaky))
+
+	for i, want := range []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK} {
+		r := httptest.NewRequest("POST", "/posts/create", nil)
+		r.Header.Set("Idempotency-Key", "k1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("request %d: status %d, want %d", i, w.Code, want)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2 (the panic, then one success replayed after)", n)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// responseRecorder remembers the status code and the number of body bytes a handler wrote,
+// which the http.ResponseWriter interface has no way of telling us afterwards.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rw *responseRecorder) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK // [1]
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.NewResponseController reach the original writer (for Flush, deadlines...).
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// NewAccessLogger builds the logger used by LoggingMiddleware.
+// In JSON mode every request is one JSON object per line, with the timestamp under "ts".
+func NewAccessLogger(w io.Writer, json bool) *slog.Logger {
+	if !json {
+		return slog.New(slog.NewTextHandler(w, nil))
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				a.Key = "ts"
+			}
+			return a
+		},
+	}))
+}
+
+// LoggingMiddleware writes one access log entry per request once the handler has returned.
+func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r)
+			logRequest(logger, r, rec, begin)
+		})
+	}
+}
+
+// SampledLoggingMiddleware is LoggingMiddleware logging at most perSecond requests per route each second,
+// so a flood of identical lines doesn't bury everything else. 5xx responses are always logged, outside the limit.
+// What's left out is counted, and reported in one "suppressed" line per route once its second is over. [3]
+func SampledLoggingMiddleware(logger *slog.Logger, perSecond int) func(http.Handler) http.Handler {
+	s := &logSampler{logger: logger, perSecond: perSecond, windows: make(map[string]*logWindow)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r)
+
+			route := "unmatched"
+			if label, ok := r.Context().Value(routeKey).(*routeLabel); ok { // needs Metrics.Middleware further out
+				route = label.pattern
+			}
+			if rec.status >= 500 || s.allow(r.Context(), route, time.Now()) { // 5xx first, they mustn't use up the budget
+				logRequest(logger, r, rec, begin)
+			}
+		})
+	}
+}
+
+func logRequest(logger *slog.Logger, r *http.Request, rec *responseRecorder, begin time.Time) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK // the handler wrote nothing at all
+	}
+	logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", rec.status),
+		slog.Int("bytes", rec.bytes),
+		slog.Float64("duration_ms", float64(time.Since(begin).Microseconds())/1000),
+		slog.String("request_id", RequestID(r.Context())),
+	)
+}
+
+type logSampler struct {
+	logger    *slog.Logger
+	perSecond int
+
+	mu      sync.Mutex
+	windows map[string]*logWindow // route -> the current second
+}
+
+type logWindow struct {
+	start      time.Time
+	logged     int
+	suppressed int
+}
+
+// allow counts a request for route and reports whether it may be logged.
+func (s *logSampler) allow(ctx context.Context, route string, now time.Time) bool {
+	type summary struct {
+		route      string
+		suppressed int
+	}
+	var done []summary
+
+	s.mu.Lock()
+	for k, w := range s.windows { // lazy, like the caches, a window is closed by the first request after it
+		if now.Sub(w.start) >= time.Second {
+			if w.suppressed > 0 {
+				done = append(done, summary{k, w.suppressed})
+			}
+			delete(s.windows, k)
+		}
+	}
+	w, ok := s.windows[route]
+	if !ok {
+		w = &logWindow{start: now}
+		s.windows[route] = w
+	}
+	allowed := w.logged < s.perSecond
+	if allowed {
+		w.logged++
+	} else {
+		w.suppressed++
+	}
+	s.mu.Unlock()
+
+	for _, d := range done { // logged outside the lock, a slow log writer shouldn't hold up every request
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "suppressed access log lines",
+			slog.String("route", d.route),
+			slog.Int("suppressed", d.suppressed),
+			slog.Int("per_second", s.perSecond),
+		)
+	}
+	return allowed
+}
+
+// SlowRequestMiddleware logs a warning for every request whose handler took longer than threshold.
+// It only watches, the request runs to completion however long it takes. [2]
+func SlowRequestMiddleware(threshold time.Duration, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			next.ServeHTTP(w, r)
+
+			if took := time.Since(begin); took > threshold {
+				logger.LogAttrs(r.Context(), slog.LevelWarn, "slow request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Float64("duration_ms", float64(took.Microseconds())/1000),
+					slog.Float64("threshold_ms", float64(threshold.Microseconds())/1000),
+					slog.String("request_id", RequestID(r.Context())),
+				)
+			}
+		})
+	}
+}
+
+// LoggerMiddleware hands l down to everything below it, which logs through logger.FromContext(r.Context()).
+// Put it near the top of the chain, anything running outside it falls back to logger.Default().
+func LoggerMiddleware(l logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(logger.NewContext(r.Context(), l)))
+		})
+	}
+}
+
+// RequestIDMiddleware tags every request with an id, reusing the client's X-Request-ID if it sent a sane one,
+// and echoes it back in the response so both sides can refer to the same request.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestID returns the id RequestIDMiddleware stored in ctx, or "" if it didn't run.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+/*
+[1] : Calling Write without WriteHeader implicitly sends a 200 OK, so that's what the client got.
+
+[2] : Cutting slow requests short is http.TimeoutHandler's (or the server's WriteTimeout's) job.
+			Knowing *which* endpoints are slow comes first, a limit picked without that data tends to be wrong.
+
+[3] : Sampling by route keeps the quiet routes fully logged while the hot one is capped, a global cap would
+			let one busy endpoint crowd every other out of the logs. The counts stay exact either way,
+			that's what /metrics is for, the log only loses the individual lines.
+			The summary for a route comes with the next request (to any route) after its second is over,
+			when traffic stops altogether the last count is only lost from the log, not from /metrics.
+*/
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// Errors and panics from a request must land in the logger LoggerMiddleware handed down, along with the request id.
+func TestLoggerMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"500 from writeError", func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, r, errors.New("db is down"))
+		}, `msg="request failed" request_id=abc err="db is down"`},
+		{"panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}, `msg="handler panicked" method=GET path=/x request_id=abc panic=boom`},
+		{"4xx not logged", func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, r, badRequest(errors.New("bad input")))
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := RequestIDMiddleware(LoggerMiddleware(logger.New(&buf, slog.LevelInfo))(RecoverMiddleware(nil)(tt.handler)))
+			req := httptest.NewRequest("GET", "/x", nil)
+			req.Header.Set("X-Request-ID", "abc")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got := buf.String(); tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("logged %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampledLoggingMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     map[string]int // status in the log line -> lines
+	}{
+		{"under the limit", []int{200, 200}, map[string]int{"200": 2}},
+		{"over the limit", []int{200, 200, 200, 200}, map[string]int{"200": 2}},
+		{"5xx always logged", []int{500, 500, 500}, map[string]int{"500": 3}},
+		{"5xx don't use up the budget", []int{500, 500, 500, 200, 200, 200}, map[string]int{"500": 3, "200": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := SampledLoggingMiddleware(NewAccessLogger(&buf, false), 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+				w.WriteHeader(status)
+			}))
+			for _, status := range tt.statuses {
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x?status="+strconv.Itoa(status), nil))
+			}
+
+			got := map[string]int{}
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if _, status, ok := strings.Cut(line, " status="); ok {
+					got[strings.Fields(status)[0]]++
+				}
+			}
+			if len(got) != len(tt.want) || got["200"] != tt.want["200"] || got["500"] != tt.want["500"] {
+				t.Errorf("logged %v, want %v\n%s", got, tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareJSON(t *testing.T) {
+	var buf bytes.Buffer
+	h := RequestIDMiddleware(LoggingMiddleware(NewAccessLogger(&buf, true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+	req := httptest.NewRequest("POST", "/posts?x=1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("not one JSON object: %q: %v", buf.String(), err)
+	}
+	want := map[string]any{"msg": "request", "method": "POST", "path": "/posts", "status": 201.0, "bytes": 5.0, "request_id": "req-1"}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["ts"].(string); !ok {
+		t.Errorf("ts = %#v, want the timestamp under ts", entry["ts"])
+	}
+	if _, ok := entry["time"]; ok {
+		t.Error("time is still there next to ts")
+	}
+	if d, ok := entry["duration_ms"].(float64); !ok || d < 0 {
+		t.Errorf("duration_ms = %#v, want a non negative number", entry["duration_ms"])
+	}
+}
+
+// A handler that writes nothing sent a 200, that's what gets logged.
+func TestLoggingMiddlewareEmptyResponse(t *testing.T) {
+	var buf bytes.Buffer
+	LoggingMiddleware(NewAccessLogger(&buf, false))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(buf.String(), "status=200 bytes=0") {
+		t.Errorf("logged %q", buf.String())
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		sent   string
+		reused bool
+	}{
+		{"none sent", "", false},
+		{"sane one reused", "abc-123", true},
+		{"too long replaced", strings.Repeat("x", 65), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = RequestID(r.Context()) }))
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.sent != "" {
+				req.Header.Set("X-Request-ID", tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if seen == "" || rec.Header().Get("X-Request-ID") != seen {
+				t.Errorf("handler saw %q, response header %q", seen, rec.Header().Get("X-Request-ID"))
+			}
+			if (seen == tt.sent) != tt.reused {
+				t.Errorf("id %q, sent %q, want reused = %v", seen, tt.sent, tt.reused)
+			}
+		})
+	}
+}
+
+func TestSlowRequestMiddleware(t *testing.T) {
+	const threshold = 20 * time.Millisecond
+	tests := []struct {
+		name     string
+		sleep    time.Duration
+		wantWarn bool
+	}{
+		{"fast", 0, false},
+		{"slow", 2 * threshold, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := RequestIDMiddleware(SlowRequestMiddleware(threshold, NewAccessLogger(&buf, true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.sleep)
+				w.Write([]byte("done"))
+			})))
+			req := httptest.NewRequest("GET", "/report?year=2024", nil)
+			req.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Body.String() != "done" {
+				t.Errorf("body = %q, want the handler to run to completion", w.Body.String())
+			}
+			if !tt.wantWarn {
+				if buf.Len() != 0 {
+					t.Errorf("logged %q for a fast request", buf.String())
+				}
+				return
+			}
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("not one JSON object: %q: %v", buf.String(), err)
+			}
+			want := map[string]any{"level": "WARN", "msg": "slow request", "method": "GET", "path": "/report", "threshold_ms": 20.0, "request_id": "req-1"}
+			for k, v := range want {
+				if entry[k] != v {
+					t.Errorf("%s = %#v, want %#v", k, entry[k], v)
+				}
+			}
+			if d, ok := entry["duration_ms"].(float64); !ok || d < float64(tt.sleep.Milliseconds()) {
+				t.Errorf("duration_ms = %#v, want at least %d", entry["duration_ms"], tt.sleep.Milliseconds())
+			}
+		})
+	}
+}
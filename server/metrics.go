@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// Metrics counts what went through the server over its lifetime, Middleware does the counting.
+type Metrics struct {
+	start    time.Time
+	requests atomic.Int64
+	inFlight atomic.Int64
+	peak     atomic.Int64
+	bytesIn  atomic.Int64 // request bodies
+	bytesOut atomic.Int64 // response bodies
+
+	mu  sync.Mutex
+	slo map[string]*[len(sloBuckets)]int64 // route pattern -> requests per latency bucket
+}
+
+// sloBuckets are the latency classes requests are counted in, e.g. "99% of requests under 300ms"
+// is (lt_100ms + lt_300ms) / total for a route. [2]
+var sloBuckets = [...]struct {
+	label string
+	below time.Duration // 0 for the last, open ended bucket
+}{
+	{"lt_100ms", 100 * time.Millisecond},
+	{"lt_300ms", 300 * time.Millisecond},
+	{"lt_1s", time.Second},
+	{"ge_1s", 0},
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{start: time.Now(), slo: make(map[string]*[len(sloBuckets)]int64)}
+}
+
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.requests.Add(1)
+		raisePeak(&m.peak, m.inFlight.Add(1))
+		defer m.inFlight.Add(-1)
+
+		if r.Body != nil {
+			r.Body = readCloser{&countingReader{r: r.Body, n: &m.bytesIn}, r.Body}
+		}
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() { m.bytesOut.Add(int64(rec.bytes)) }()
+
+		label := &routeLabel{pattern: "unmatched"}
+		begin := time.Now()
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey, label)))
+		m.observe(label.pattern, time.Since(begin))
+	})
+}
+
+func (m *Metrics) observe(route string, took time.Duration) {
+	i := len(sloBuckets) - 1
+	for j, b := range sloBuckets[:len(sloBuckets)-1] {
+		if took < b.below {
+			i = j
+			break
+		}
+	}
+
+	m.mu.Lock()
+	counts, ok := m.slo[route]
+	if !ok {
+		counts = new([len(sloBuckets)]int64)
+		m.slo[route] = counts
+	}
+	counts[i]++
+	m.mu.Unlock()
+}
+
+// routeLabel is filled in by RouteRegistry's routes once the mux has matched one, so requests are grouped
+// by pattern ("/user/{id}") rather than by path, which would make one entry per user. [3]
+type routeLabel struct {
+	pattern string
+}
+
+func labelRoute(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if label, ok := r.Context().Value(routeKey).(*routeLabel); ok {
+			label.pattern = pattern
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP serves the counters as JSON.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	slo := make(map[string]map[string]int64, len(m.slo))
+	for route, counts := range m.slo {
+		buckets := make(map[string]int64, len(sloBuckets))
+		for i, b := range sloBuckets {
+			buckets[b.label] = counts[i]
+		}
+		slo[route] = buckets
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"uptime_seconds":   int(time.Since(m.start).Seconds()),
+		"requests":         m.requests.Load(),
+		"in_flight":        m.inFlight.Load(),
+		"peak_concurrency": m.peak.Load(),
+		"bytes_in":         m.bytesIn.Load(),
+		"bytes_out":        m.bytesOut.Load(),
+		"latency_buckets":  slo,
+	})
+}
+
+// LogReport logs the end-of-run summary.
+func (m *Metrics) LogReport(l logger.Logger) {
+	l.Info("shutdown report",
+		slog.Int64("requests", m.requests.Load()),
+		slog.Duration("uptime", time.Since(m.start).Round(time.Millisecond)),
+		slog.Int64("peak_concurrency", m.peak.Load()),
+		slog.Int64("bytes_in", m.bytesIn.Load()),
+		slog.Int64("bytes_out", m.bytesOut.Load()),
+	)
+}
+
+// raisePeak sets peak to n if n is higher, retrying when another request moved it in between. [1]
+func raisePeak(peak *atomic.Int64, n int64) {
+	for {
+		current := peak.Load()
+		if n <= current || peak.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+/*
+[1] : A plain "if n > peak { peak = n }" is a read followed by a write, two requests starting together could
+			both read the old peak and the smaller of them could win. CompareAndSwap only writes if nobody did in between.
+
+[2] : Averages hide the slow tail an SLO is about, a route averaging 50ms can still have 5% of requests over a second.
+			Counting per bucket keeps that visible at the cost of a few integers per route.
+
+[3] : The pattern is only known after the mux matched the route, deep inside the chain, while the clock runs
+			out here. Hence a pointer in the context the route writes to, and we read back once it returned.
+*/
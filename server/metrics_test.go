@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetricsShutdownReport(t *testing.T) {
+	m := NewMetrics()
+	release := make(chan struct{})
+	var entered sync.WaitGroup
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/slow" {
+			entered.Done()
+			<-release
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	srv := httptest.NewServer(h)
+
+	var wg sync.WaitGroup
+	do := func(method, path, body string) {
+		defer wg.Done()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+	// 3 requests in flight at once, then 2 more one after the other
+	entered.Add(3)
+	wg.Add(3)
+	for range 3 {
+		go do("POST", "/slow", "hello")
+	}
+	entered.Wait()
+	close(release)
+	wg.Wait()
+	wg.Add(2)
+	do("GET", "/", "")
+	do("POST", "/", "abc")
+	srv.Close() // like main shuts the server down before logging the report
+
+	var logs bytes.Buffer
+	m.LogReport(NewAccessLogger(&logs, true))
+	var report struct {
+		Msg             string `json:"msg"`
+		Requests        int64  `json:"requests"`
+		PeakConcurrency int64  `json:"peak_concurrency"`
+		BytesIn         int64  `json:"bytes_in"`
+		BytesOut        int64  `json:"bytes_out"`
+		Uptime          int64  `json:"uptime"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &report); err != nil {
+		t.Fatalf("%v: %s", err, logs.String())
+	}
+
+	want := struct{ requests, peak, in, out int64 }{5, 3, 3*5 + 3, 5 * 10}
+	got := struct{ requests, peak, in, out int64 }{report.Requests, report.PeakConcurrency, report.BytesIn, report.BytesOut}
+	if report.Msg != "shutdown report" || got != want {
+		t.Errorf("report %q %+v, want %+v", report.Msg, got, want)
+	}
+	if report.Uptime <= 0 {
+		t.Errorf("uptime = %d", report.Uptime)
+	}
+}
+
+func TestMetricsObserveBuckets(t *testing.T) {
+	tests := []struct {
+		took time.Duration
+		want string
+	}{
+		{0, "lt_100ms"},
+		{99 * time.Millisecond, "lt_100ms"},
+		{100 * time.Millisecond, "lt_300ms"},
+		{299 * time.Millisecond, "lt_300ms"},
+		{300 * time.Millisecond, "lt_1s"},
+		{time.Second, "ge_1s"},
+		{time.Minute, "ge_1s"},
+	}
+	for _, tt := range tests {
+		m := NewMetrics()
+		m.observe("GET /x", tt.took)
+		if got := sloCounts(t, m)["GET /x"]; got[tt.want] != 1 || sum(got) != 1 {
+			t.Errorf("%v counted as %v, want one in %s", tt.took, got, tt.want)
+		}
+	}
+}
+
+// Requests are counted under the pattern that matched them, in the bucket their latency falls in.
+func TestMetricsLatencyBuckets(t *testing.T) {
+	m := NewMetrics()
+	mux := http.NewServeMux()
+	routes := NewRouteRegistry(mux)
+	routes.HandleFunc("GET /user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		d, _ := time.ParseDuration(r.URL.Query().Get("sleep"))
+		time.Sleep(d)
+	})
+	h := m.Middleware(mux)
+
+	var wg sync.WaitGroup
+	for _, target := range []string{"/user/1", "/user/2", "/user/3?sleep=150ms", "/user/4?sleep=400ms", "/nope"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+		}()
+	}
+	wg.Wait()
+
+	want := map[string]map[string]int64{
+		"GET /user/{id}": {"lt_100ms": 2, "lt_300ms": 1, "lt_1s": 1, "ge_1s": 0},
+		"unmatched":      {"lt_100ms": 1, "lt_300ms": 0, "lt_1s": 0, "ge_1s": 0},
+	}
+	if got := sloCounts(t, m); !reflect.DeepEqual(got, want) {
+		t.Errorf("latency_buckets = %v, want %v", got, want)
+	}
+}
+
+// sloCounts reads the latency buckets the way a client would, off the metrics endpoint.
+func sloCounts(t *testing.T, m *Metrics) map[string]map[string]int64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	var body struct {
+		Buckets map[string]map[string]int64 `json:"latency_buckets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Buckets
+}
+
+func sum(counts map[string]int64) (n int64) {
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
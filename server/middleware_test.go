@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestBodyLogMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantLogged  []string
+		wantHidden  []string
+	}{
+		{"password masked", "application/json", `{"user":"amit","password":"hunter2"}`,
+			[]string{`"user":"amit"`, `"password":"***"`}, []string{"hunter2"}},
+		{"nested and any case", "application/json; charset=utf-8", `{"auth":{"Token":"abc123"},"items":[{"password":"p"}]}`,
+			[]string{`"Token":"***"`, `"password":"***"`}, []string{"abc123", `"p"`}},
+		{"invalid json", "application/json", `{"password":`, []string{`"invalid_json_bytes":12`}, []string{"password"}},
+		{"not json, not logged", "text/plain", `password=hunter2`, nil, []string{"hunter2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged bytes.Buffer
+			var received string
+			h := LoggerMiddleware(NewAccessLogger(&logged, true))(BodyLogMiddleware([]string{"password", "token"})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					b, _ := io.ReadAll(r.Body)
+					received = string(b)
+				})))
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if received != tt.body {
+				t.Errorf("handler read %q, want the original %q", received, tt.body)
+			}
+			var entry map[string]any
+			json.Unmarshal(logged.Bytes(), &entry) // one line, or none
+			line := logged.String()
+			if body, ok := entry["body"].(string); ok {
+				line = body // unescaped, as the handler would have seen it
+			}
+			for _, want := range tt.wantLogged {
+				if !strings.Contains(line, want) {
+					t.Errorf("log %q doesn't contain %q", line, want)
+				}
+			}
+			for _, hidden := range tt.wantHidden {
+				if strings.Contains(line, hidden) {
+					t.Errorf("log %q contains %q", line, hidden)
+				}
+			}
+		})
+	}
+}
+
+// A body over maxLoggedBody is only partly logged, the handler still gets all of it.
+func TestBodyLogMiddlewareLargeBody(t *testing.T) {
+	body := `{"data":"` + strings.Repeat("x", maxLoggedBody) + `"}`
+	var received int
+	h := LoggerMiddleware(logger.Nop)(BodyLogMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = len(b)
+	})))
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if received != len(body) {
+		t.Errorf("handler read %d bytes, want %d", received, len(body))
+	}
+}
+
+func TestRequireJSONMiddleware(t *testing.T) {
+	tests := []struct {
+		method, contentType string
+		wantStatus          int
+	}{
+		{"POST", "application/json", 200},
+		{"PUT", "application/json; charset=utf-8", 200},
+		{"PATCH", "Application/JSON", 200},
+		{"POST", "application/x-www-form-urlencoded", 415},
+		{"POST", "", 415},
+		{"POST", "application/json;;", 415},
+		{"GET", "", 200}, // nothing to decode
+		{"DELETE", "text/plain", 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.contentType, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			RequireJSONMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMaxResponseBytesMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		writes        []string
+		contentLength string // set by the handler, if any
+		wantBody      string
+		wantWarning   bool
+	}{
+		{"under the limit", []string{"hello"}, "", "hello", false},
+		{"exactly the limit", []string{"0123456789"}, "", "0123456789", false},
+		{"one big write", []string{"0123456789abcdef"}, "", "0123456789", true},
+		{"many writes", []string{"0123", "4567", "89ab", "cdef"}, "", "0123456789", true},
+		{"content-length too large", []string{"0123456789abcdef"}, "16", "0123456789", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			var writeErr error
+			h := LoggerMiddleware(logger.New(&logs, slog.LevelInfo))(MaxResponseBytesMiddleware(10)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if tt.contentLength != "" {
+						w.Header().Set("Content-Length", tt.contentLength)
+					}
+					for _, s := range tt.writes {
+						if _, err := w.Write([]byte(s)); err != nil {
+							writeErr = err
+						}
+					}
+				})))
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			res, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatalf("reading the body: %v", err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("client got %q, want %q", body, tt.wantBody)
+			}
+			if tt.wantWarning != errors.Is(writeErr, ErrResponseTooLarge) {
+				t.Errorf("handler's Write error = %v", writeErr)
+			}
+			if got := strings.Count(logs.String(), "response truncated"); got != map[bool]int{true: 1}[tt.wantWarning] {
+				t.Errorf("logged %d warnings:\n%s", got, logs.String())
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		max        int
+		inFlight   int // slow requests held open first
+		wantStatus int // of the next one
+	}{
+		{"under the limit", 3, 2, http.StatusOK},
+		{"at the limit", 3, 3, http.StatusServiceUnavailable},
+		{"zero is unlimited", 0, 10, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			started := make(chan struct{})
+			h := ConcurrencyLimitMiddleware(tt.max)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					started <- struct{}{}
+					<-release
+				}
+			}))
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			done := make(chan struct{})
+			for range tt.inFlight {
+				go func() {
+					defer func() { done <- struct{}{} }()
+					res, err := http.Get(srv.URL + "/slow")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					res.Body.Close()
+					if res.StatusCode != http.StatusOK {
+						t.Errorf("held request got %d", res.StatusCode)
+					}
+				}()
+				<-started
+			}
+
+			res, err := http.Get(srv.URL + "/fast")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && res.Header.Get("Retry-After") == "" {
+				t.Error("503 without Retry-After")
+			}
+
+			close(release)
+			for range tt.inFlight {
+				<-done
+			}
+		})
+	}
+}
+
+func TestQueryLimitMiddleware(t *testing.T) {
+	h := QueryLimitMiddleware(64, 3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"no query", "", http.StatusOK},
+		{"normal", "?page=2&sort=new", http.StatusOK},
+		{"at the param limit", "?a=1&b=2&c=3", http.StatusOK},
+		{"empty parts don't count", "?a=1&&b=2&&&c=3&", http.StatusOK},
+		{"too many params", "?a=1&b=2&c=3&d=4", http.StatusBadRequest},
+		{"repeated param", "?a=1&a=2&a=3&a=4", http.StatusBadRequest},
+		{"at the byte limit", "?q=" + strings.Repeat("x", 62), http.StatusOK},
+		{"too long", "?q=" + strings.Repeat("x", 63), http.StatusRequestURITooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/search"+tt.query, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathDepthMiddleware(t *testing.T) {
+	h := PathDepthMiddleware(3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/", http.StatusOK},
+		{"/posts", http.StatusOK},
+		{"/user/42/posts", http.StatusOK},
+		{"/user/42/posts/", http.StatusOK}, // a trailing slash isn't a segment
+		{"/a//b", http.StatusOK},
+		{"/user/42/posts/7", http.StatusBadRequest},
+		{"/a///b", http.StatusBadRequest}, // empty segments count
+		{"/" + strings.Repeat("a/", 100), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %.40s: status = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// RequirePathValue returns the value of the {name} wildcard, or an error if it's missing or empty.
+// r.PathValue() returns "" both for an empty segment and for a route that has no such wildcard at all,
+// so handlers should not trust it blindly.
+func RequirePathValue(r *http.Request, name string) (string, error) {
+	value := r.PathValue(name)
+	if value == "" {
+		return "", badRequest(fmt.Errorf("missing path value %q", name))
+	}
+	return value, nil
+}
+
+// PathInt parses the {name} wildcard as a base 10 integer.
+func PathInt(r *http.Request, name string) (int, error) {
+	value, err := RequirePathValue(r, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, badRequest(fmt.Errorf("path value %q must be an integer", name))
+	}
+	return n, nil
+}
+
+// PathUUID checks that the {name} wildcard is a UUID in its canonical 8-4-4-4-12 hex form.
+func PathUUID(r *http.Request, name string) (string, error) {
+	value, err := RequirePathValue(r, name)
+	if err != nil {
+		return "", err
+	}
+	if !isUUID(value) {
+		return "", badRequest(fmt.Errorf("path value %q must be a UUID", name))
+	}
+	return value, nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePathValue(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          string
+		wantErr       bool
+	}{
+		{"/user/{id}", "/user/42", "42", false},
+		{"/other/{name}", "/other/x", "", true}, // a route without an {id} at all
+		{"/user/", "/user/", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mux := http.NewServeMux()
+			var got string
+			var err error
+			mux.HandleFunc(tt.pattern, func(w http.ResponseWriter, r *http.Request) {
+				got, err = RequirePathValue(r, "id")
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("got %q, %v, want %q (error: %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleUserById(t *testing.T) {
+	tests := []struct {
+		name, pattern, path string
+		wantStatus          int
+		wantBody            string
+	}{
+		{"present id", "/user/{id}", "/user/42", 200, "Hello user 42"},
+		{"registered without the wildcard", "/user/", "/user/", 400, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc(tt.pattern, handleUserById)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.wantStatus || tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.path, rec.Code, rec.Body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPathIntAndUUID(t *testing.T) {
+	tests := []struct {
+		name       string
+		parse      func(*http.Request) (any, error)
+		value      string
+		want       any
+		wantStatus int // of writeError for the error, 0 when there's none
+	}{
+		{"int", func(r *http.Request) (any, error) { return PathInt(r, "v") }, "42", 42, 0},
+		{"negative int", func(r *http.Request) (any, error) { return PathInt(r, "v") }, "-7", -7, 0},
+		{"not an int", func(r *http.Request) (any, error) { return PathInt(r, "v") }, "abc", 0, 400},
+		{"uuid", func(r *http.Request) (any, error) { return PathUUID(r, "v") }, "123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000", 0},
+		{"uuid upper case", func(r *http.Request) (any, error) { return PathUUID(r, "v") }, "123E4567-E89B-12D3-A456-426614174000", "123E4567-E89B-12D3-A456-426614174000", 0},
+		{"uuid too short", func(r *http.Request) (any, error) { return PathUUID(r, "v") }, "123e4567-e89b-12d3-a456", "", 400},
+		{"uuid misplaced dash", func(r *http.Request) (any, error) { return PathUUID(r, "v") }, "123e4567e-89b-12d3-a456-426614174000", "", 400},
+		{"uuid not hex", func(r *http.Request) (any, error) { return PathUUID(r, "v") }, "123e4567-e89b-12d3-a456-42661417400g", "", 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.SetPathValue("v", tt.value)
+			got, err := tt.parse(req)
+			if err == nil {
+				if tt.wantStatus != 0 || got != tt.want {
+					t.Errorf("got %v, want %v (status %d)", got, tt.want, tt.wantStatus)
+				}
+				return
+			}
+			rec := httptest.NewRecorder()
+			writeError(rec, req, err)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%v answered with %d, want %d", err, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
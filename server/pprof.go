@@ -0,0 +1,65 @@
+/*
+Profiling endpoints, only mounted when the server runs with -debug:
+
+	go run ./server -debug
+	go tool pprof http://localhost:3000/debug/pprof/heap
+	go tool pprof http://localhost:3000/debug/pprof/profile?seconds=10   # cpu
+	curl http://localhost:3000/debug/pprof/goroutine?debug=2             # every goroutine's stack
+
+They show the insides of the process (command line, stacks, memory), never expose them publicly. [1]
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MountPProf registers the net/http/pprof handlers on mux under prefix, e.g. "/debug/pprof/".
+func MountPProf(mux *http.ServeMux, prefix string) {
+	prefix = "/" + strings.Trim(prefix, "/") + "/"
+
+	// pprof.Index finds the profile name by cutting "/debug/pprof/" off the path, so put that back in front. [2]
+	mux.Handle(prefix, http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/debug/pprof/" + r.URL.Path
+		pprof.Index(w, r)
+	})))
+	mux.HandleFunc(prefix+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(prefix+"profile", outlastWriteTimeout(pprof.Profile, 30))
+	mux.HandleFunc(prefix+"symbol", pprof.Symbol)
+	mux.HandleFunc(prefix+"trace", outlastWriteTimeout(pprof.Trace, 1))
+}
+
+// outlastWriteTimeout pushes the write deadline past the ?seconds (defaultSeconds if unset) a profile or trace
+// records for, so the server's WriteTimeout doesn't cut them short: a plain "go tool pprof .../profile"
+// asks for 30s, as long as DefaultTimeouts.Write. [3]
+func outlastWriteTimeout(h http.HandlerFunc, defaultSeconds float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sec, err := strconv.ParseFloat(r.FormValue("seconds"), 64)
+		if err != nil || sec <= 0 {
+			sec = defaultSeconds
+		}
+		deadline := time.Now().Add(time.Duration(sec*float64(time.Second)) + 10*time.Second)
+		if http.NewResponseController(w).SetWriteDeadline(deadline) == nil {
+			r = r.WithContext(context.WithValue(r.Context(), http.ServerContextKey, &http.Server{}))
+		}
+		h(w, r)
+	}
+}
+
+/*
+[1] : Importing net/http/pprof also registers the same handlers on http.DefaultServeMux.
+			That's harmless here, NewServer refuses to serve the default mux (see httpserver.go).
+
+[2] : The index page links to the profiles relatively ("heap?debug=1"), those links keep working under any prefix.
+
+[3] : Go 1.23's pprof moves the deadline itself, but go.mod still allows 1.22, whose pprof refuses with a 400 any
+			duration that reaches the WriteTimeout of the server it finds in the request's context. So once the deadline
+			is moved, the handler is handed a server without one. If the deadline can't be moved the 400 stays,
+			better than a cut off profile.
+*/
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMountPProf(t *testing.T) {
+	tests := []struct {
+		name       string
+		debug      bool
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"index", true, "/debug/pprof/", http.StatusOK, "Types of profiles available"},
+		{"profile by name", true, "/debug/pprof/goroutine?debug=1", http.StatusOK, "goroutine profile:"},
+		{"cmdline", true, "/debug/pprof/cmdline", http.StatusOK, ""},
+		{"unknown profile", true, "/debug/pprof/nope", http.StatusNotFound, ""},
+		{"index disabled", false, "/debug/pprof/", http.StatusNotFound, ""},
+		{"cmdline disabled", false, "/debug/pprof/cmdline", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newMux(&DegradedMode{}, NewMetrics(), tt.debug)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %.80q, want %d containing %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+// Under another prefix the index and the profiles it links to still work.
+func TestMountPProfPrefix(t *testing.T) {
+	mux := http.NewServeMux()
+	MountPProf(mux, "internal/prof")
+	for _, path := range []string{"/internal/prof/", "/internal/prof/heap?debug=1", "/internal/prof/cmdline"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /debug/pprof/ = %d, want 404 with another prefix", w.Code)
+	}
+}
+
+// A profile or trace longer than the server's WriteTimeout still comes back whole.
+func TestMountPProfOutlastsWriteTimeout(t *testing.T) {
+	srv := httptest.NewUnstartedServer(newMux(&DegradedMode{}, NewMetrics(), true))
+	srv.Config.WriteTimeout = 500 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	for _, path := range []string{"/debug/pprof/profile?seconds=1", "/debug/pprof/trace?seconds=1"} {
+		t.Run(path, func(t *testing.T) {
+			res, err := srv.Client().Get(srv.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			b, err := io.ReadAll(res.Body)
+			if err != nil || res.StatusCode != http.StatusOK || len(b) == 0 {
+				t.Errorf("got %d, %d bytes, %v, want 200 and a profile", res.StatusCode, len(b), err)
+			}
+		})
+	}
+}
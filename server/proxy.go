@@ -0,0 +1,92 @@
+/*
+A minimal reverse proxy.
+
+The client talks to us, we forward the request to target and copy the answer back.
+httputil.ReverseProxy does this (and much more) for real, this one is small enough to read in one go.
+
+Hop-by-hop headers describe a single connection (client <-> us, or us <-> target), not the request itself,
+so they must not be forwarded (RFC 7230, section 6.1). Think of "Connection: close": the client wants to close
+*its* connection to us, which says nothing about our connection to the target.
+*/
+
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection", // non-standard, but still sent by some old clients
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopByHopHeaders deletes the hop-by-hop headers from h,
+// including any extra ones the sender listed in its Connection header. [1]
+func removeHopByHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		h.Del(name)
+	}
+}
+
+// NewProxy forwards every request to target, keeping the request's path below target's.
+//
+//	routes.Handle("/api/", NewProxy(&url.URL{Scheme: "http", Host: "localhost:8080"}))
+func NewProxy(target *url.URL) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := r.Clone(r.Context()) // deep copies the headers, so we don't modify the incoming request
+		out.RequestURI = ""         // only set on server requests, the transport refuses requests that have it
+		out.URL.Scheme = target.Scheme
+		out.URL.Host = target.Host
+		out.URL.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+		out.URL.RawPath = ""
+		out.Host = target.Host
+
+		removeHopByHopHeaders(out.Header)
+		if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			if prior := out.Header.Get("X-Forwarded-For"); prior != "" {
+				ip = prior + ", " + ip
+			}
+			out.Header.Set("X-Forwarded-For", ip)
+		}
+
+		res, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("proxy", "target", target.Host, "err", err)
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+
+		removeHopByHopHeaders(res.Header)
+		for k, v := range res.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(res.StatusCode)
+		io.Copy(w, res.Body)
+	})
+}
+
+/*
+[1] : "Connection: X-Debug-Session" marks X-Debug-Session as hop-by-hop too, so the list of headers
+			to strip isn't fixed, it has to be read from the Connection header first.
+*/
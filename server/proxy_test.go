@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestRemoveHopByHopHeaders(t *testing.T) {
+	h := http.Header{
+		"Connection":          {"keep-alive, X-Debug-Session", "x-trace"},
+		"Keep-Alive":          {"timeout=5"},
+		"Proxy-Authenticate":  {"Basic"},
+		"Proxy-Authorization": {"Basic abc"},
+		"Proxy-Connection":    {"keep-alive"},
+		"Te":                  {"trailers"},
+		"Trailer":             {"X-Checksum"},
+		"Transfer-Encoding":   {"chunked"},
+		"Upgrade":             {"websocket"},
+		"X-Debug-Session":     {"1"},
+		"X-Trace":             {"abc"},
+		"Accept":              {"application/json"},
+		"Authorization":       {"Bearer token"},
+	}
+	removeHopByHopHeaders(h)
+
+	var left []string
+	for k := range h {
+		left = append(left, k)
+	}
+	slices.Sort(left)
+	if want := []string{"Accept", "Authorization"}; !slices.Equal(left, want) {
+		t.Errorf("headers left: %v, want %v", left, want)
+	}
+}
+
+func TestNewProxy(t *testing.T) {
+	var got *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Connection", "X-Internal")
+		w.Header().Set("X-Internal", "leak")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("from upstream"))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL + "/base/")
+	proxy := httptest.NewServer(NewProxy(target))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("GET", proxy.URL+"/posts?page=2", nil)
+	req.Header.Set("Connection", "X-Debug-Session")
+	req.Header.Set("X-Debug-Session", "1")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("Proxy-Authorization", "Basic abc")
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("Te", "trailers")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	req.Header.Set("Authorization", "Bearer token")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	for _, name := range []string{"Connection", "X-Debug-Session", "Keep-Alive", "Proxy-Authorization", "Upgrade", "Te"} {
+		if v := got.Header.Get(name); v != "" {
+			t.Errorf("forwarded request has %s: %q", name, v)
+		}
+	}
+	tests := []struct{ name, got, want string }{
+		{"path", got.URL.RequestURI(), "/base/posts?page=2"},
+		{"Authorization", got.Header.Get("Authorization"), "Bearer token"},
+		{"X-Forwarded-For", got.Header.Get("X-Forwarded-For"), "10.0.0.1, 127.0.0.1"},
+		{"response X-Upstream", res.Header.Get("X-Upstream"), "yes"},
+		{"response X-Internal", res.Header.Get("X-Internal"), ""},
+		{"response Keep-Alive", res.Header.Get("Keep-Alive"), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if res.StatusCode != http.StatusTeapot {
+		t.Errorf("status = %d, want the upstream's 418", res.StatusCode)
+	}
+}
+
+func TestNewProxyBadGateway(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target, _ := url.Parse(upstream.URL)
+	upstream.Close()
+
+	w := httptest.NewRecorder()
+	LoggerMiddleware(logger.Nop)(NewProxy(target)).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Per-client quotas: "at most N requests per window".
+
+A fixed window (reset the counter every minute on the minute) lets a client send N requests at 0:59
+and N more at 1:00, twice the quota within two seconds. A sliding window counter smooths that out
+without remembering every request: it keeps the count of the current and the previous window and
+weighs the previous one by how much of it still overlaps the last window-long stretch of time.
+	estimate = previous * (1 - elapsed/window) + current
+e.g. 30s into a 1m window: half of last window's requests still count against us.
+*/
+
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type windowCounter struct {
+	start    time.Time // beginning of the current window
+	current  int
+	previous int
+}
+
+type slidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*windowCounter
+	lastSweep time.Time
+}
+
+// RateLimitMiddleware allows each client limit requests per window, and answers a 429 past that.
+// Clients are told apart by the principal an auth middleware (PrincipalFromAPIKeyMiddleware) vouched for, or by IP
+// for anonymous requests, so it has to run after authentication. Every response carries
+// X-RateLimit-Remaining, and X-RateLimit-Reset, the seconds until the current window ends.
+func RateLimitMiddleware(limit int, window time.Duration) func(http.Handler) http.Handler {
+	l := &slidingWindowLimiter{limit: limit, window: window, clients: make(map[string]*windowCounter)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + clientIP(r)
+			if name := Principal(r.Context()); name != "" {
+				key = "user:" + name // [2]
+			}
+
+			allowed, remaining, reset := l.allow(key, time.Now())
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+				http.Error(w, "Too many requests, slow down", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow counts a request from key at now, unless it's over the limit.
+func (l *slidingWindowLimiter) allow(key string, now time.Time) (allowed bool, remaining int, reset time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	windowStart := now.Truncate(l.window)
+	l.sweep(windowStart)
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &windowCounter{start: windowStart}
+		l.clients[key] = c
+	}
+	if !c.start.Equal(windowStart) { // roll over into the new window
+		if windowStart.Sub(c.start) == l.window {
+			c.previous = c.current
+		} else {
+			c.previous = 0 // the client skipped at least a whole window
+		}
+		c.current = 0
+		c.start = windowStart
+	}
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	estimate := int(float64(c.previous)*weight) + c.current
+	reset = l.window - elapsed
+
+	if estimate >= l.limit {
+		return false, 0, reset
+	}
+	c.current++
+	return true, l.limit - estimate - 1, reset
+}
+
+// sweep forgets clients that haven't been seen for two windows, once per window. [1]
+func (l *slidingWindowLimiter) sweep(windowStart time.Time) {
+	if windowStart.Equal(l.lastSweep) {
+		return
+	}
+	l.lastSweep = windowStart
+	for key, c := range l.clients {
+		if windowStart.Sub(c.start) > l.window {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// clientIP is the IP the request came from, without the port.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+/*
+[1] : Without it the map keeps one entry for every client that ever talked to us.
+			Deleting from a map while ranging over it is fine in Go.
+
+[2] : Only ever key on something the server checked. Keying on the raw X-API-Key header would hand out a fresh
+			quota for every made up key, a client could just send a random one with each request.
+			An unknown key doesn't authenticate anyone, so that request counts against its IP like any anonymous one.
+*/
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		keys   func(i int) string // the X-API-Key of request i
+		from   func(i int) string // its RemoteAddr
+		wantOK int                // how many of the 5 requests get through
+	}{
+		{"anonymous, one ip", func(int) string { return "" }, func(int) string { return "1.2.3.4:1000" }, 3},
+		{"anonymous, many ips", func(int) string { return "" }, func(i int) string { return fmt.Sprintf("1.2.3.%d:1000", i) }, 5},
+		{"valid key, many ips", func(int) string { return "demo-key-amit" }, func(i int) string { return fmt.Sprintf("1.2.3.%d:1000", i) }, 3},
+		{"random key each time", func(i int) string { return fmt.Sprintf("made-up-%d", i) }, func(int) string { return "1.2.3.4:1000" }, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := PrincipalFromAPIKeyMiddleware(APIKeys)(RateLimitMiddleware(3, time.Minute)(ok))
+			passed := 0
+			for i := range 5 {
+				r := httptest.NewRequest("GET", "/", nil)
+				r.RemoteAddr = tt.from(i)
+				if key := tt.keys(i); key != "" {
+					r.Header.Set("X-API-Key", key)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				switch w.Code {
+				case http.StatusOK:
+					passed++
+				case http.StatusTooManyRequests:
+					if w.Header().Get("Retry-After") == "" {
+						t.Error("429 without Retry-After")
+					}
+				default:
+					t.Fatalf("unexpected status %d", w.Code)
+				}
+			}
+			if passed != tt.wantOK {
+				t.Errorf("%d requests got through, want %d", passed, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowWeighsPreviousWindow(t *testing.T) {
+	l := &slidingWindowLimiter{limit: 10, window: time.Minute, clients: make(map[string]*windowCounter)}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for range 10 {
+		if ok, _, _ := l.allow("c", start.Add(50*time.Second)); !ok {
+			t.Fatal("request within the limit rejected")
+		}
+	}
+	// 30s into the next window half of the previous 10 still count, so 5 more fit.
+	passed := 0
+	for range 10 {
+		if ok, _, _ := l.allow("c", start.Add(90*time.Second)); ok {
+			passed++
+		}
+	}
+	if passed != 5 {
+		t.Errorf("%d requests allowed halfway into the next window, want 5", passed)
+	}
+}
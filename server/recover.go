@@ -0,0 +1,87 @@
+/*
+Panic recovery.
+
+net/http already recovers panics in handlers, but all it does is log the stack and slam the connection shut,
+so the client gets no response at all. RecoverMiddleware catches the panic first and turns it into a proper 500.
+
+How that 500 looks depends on who is asking: an API client wants JSON, a browser wants a page.
+So the response is built by a PanicMapper, and different routes can be wrapped with different mappers:
+	mux.Handle("/api/", RecoverMiddleware(JSONPanicMapper)(api))
+	mux.Handle("/", RecoverMiddleware(HTMLPanicMapper)(home{}))
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// PanicMapper writes the response for a request whose handler panicked with recovered.
+type PanicMapper func(w http.ResponseWriter, r *http.Request, recovered any)
+
+// RecoverMiddleware recovers panics from next and hands them to mapper (plain text 500 if nil).
+func RecoverMiddleware(mapper PanicMapper) func(http.Handler) http.Handler {
+	if mapper == nil {
+		mapper = TextPanicMapper
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec) // [1]
+				}
+				logger.FromContext(r.Context()).Error("handler panicked", "method", r.Method, "path", r.URL.Path,
+					"request_id", RequestID(r.Context()), "panic", rec, "stack", string(debug.Stack()))
+				mapper(w, r, rec)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TextPanicMapper(w http.ResponseWriter, r *http.Request, recovered any) {
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
+
+func JSONPanicMapper(w http.ResponseWriter, r *http.Request, recovered any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":      "internal server error", // never the recovered value itself, it may leak internals
+		"request_id": RequestID(r.Context()),
+	})
+}
+
+func HTMLPanicMapper(w http.ResponseWriter, r *http.Request, recovered any) {
+	templ, err := template.ParseFiles("templates/error.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("error page template", "err", err)
+		TextPanicMapper(w, r, recovered)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	templ.Execute(w, map[string]any{
+		"Status":     http.StatusInternalServerError,
+		"StatusText": http.StatusText(http.StatusInternalServerError),
+		"RequestID":  RequestID(r.Context()),
+	})
+}
+
+/*
+[1] : http.ErrAbortHandler is the sentinel a handler panics with to deliberately abort the response,
+			net/http handles it (without even logging a stack), so it's not ours to swallow.
+
+Note: if the handler already started writing its response before panicking, the status line is gone
+and the mapper's output just gets appended. There's no way to take bytes back once they're sent.
+*/
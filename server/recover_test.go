@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// inRepoRoot runs the test from the repository root, where the templates/ directory is,
+// like the server itself is run (go run ./server).
+func inRepoRoot(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	inRepoRoot(t)
+	boom := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	tests := []struct {
+		name            string
+		mapper          PanicMapper
+		wantContentType string
+		check           func(t *testing.T, body string)
+	}{
+		{"json route", JSONPanicMapper, "application/json", func(t *testing.T, body string) {
+			var got map[string]string
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("body %q is not JSON: %v", body, err)
+			}
+			if got["error"] != "internal server error" || got["request_id"] != "req-1" {
+				t.Errorf("got %v", got)
+			}
+		}},
+		{"html route", HTMLPanicMapper, "text/html; charset=utf-8", func(t *testing.T, body string) {
+			if !strings.HasPrefix(body, "<!DOCTYPE html>") || !strings.Contains(body, "<h1>500 Internal Server Error</h1>") ||
+				!strings.Contains(body, "<code>req-1</code>") {
+				t.Errorf("not the error page: %q", body)
+			}
+		}},
+		{"default", nil, "text/plain; charset=utf-8", func(t *testing.T, body string) {
+			if body != "Internal Server Error\n" {
+				t.Errorf("body = %q", body)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := RequestIDMiddleware(LoggerMiddleware(logger.Nop)(RecoverMiddleware(tt.mapper)(boom)))
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want 500", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			tt.check(t, w.Body.String())
+		})
+	}
+}
+
+func TestRecoverMiddlewareErrAbortHandler(t *testing.T) {
+	h := RecoverMiddleware(JSONPanicMapper)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler to be re-panicked", rec)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
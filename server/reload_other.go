@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "net/http"
+
+// ReloadOnSignal is a no-op where SIGHUP doesn't exist, Reload can still be called from code.
+func (h *ReloadableHandler) ReloadOnSignal(build func() *http.ServeMux) {}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestReloadableHandler(t *testing.T) {
+	v1 := http.NewServeMux()
+	v1.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("v1")) })
+	v2 := http.NewServeMux()
+	v2.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("v2")) })
+	v2.HandleFunc("GET /new", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("new")) })
+
+	h := NewReloadableHandler(v1)
+	h.Logger = logger.Nop
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	tests := []struct {
+		reload     *http.ServeMux // swapped in before the request, nil keeps the current one
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{nil, "/hello", http.StatusOK, "v1"},
+		{nil, "/new", http.StatusNotFound, ""},
+		{v2, "/hello", http.StatusOK, "v2"},
+		{nil, "/new", http.StatusOK, "new"},
+		{v1, "/new", http.StatusNotFound, ""},
+	}
+	for i, tt := range tests {
+		if tt.reload != nil {
+			h.Reload(tt.reload)
+		}
+		status, body := get(tt.path)
+		if status != tt.wantStatus || tt.wantBody != "" && body != tt.wantBody {
+			t.Errorf("step %d, GET %s = %d %q, want %d %q", i, tt.path, status, body, tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+// A request that started before Reload finishes on the mux it started with.
+func TestReloadableHandlerInFlight(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	old := http.NewServeMux()
+	old.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.Write([]byte("old"))
+	})
+	h := NewReloadableHandler(old)
+	h.Logger = logger.Nop
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	got := make(chan string)
+	go func() {
+		res, err := http.Get(srv.URL)
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		got <- string(b)
+	}()
+	<-entered
+	h.Reload(http.NewServeMux()) // 404s for everything
+	close(release)
+
+	if body := <-got; body != "old" {
+		t.Errorf("in flight request got %q, want the old mux's answer", body)
+	}
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("request after Reload = %d, want the new mux's 404", res.StatusCode)
+	}
+}
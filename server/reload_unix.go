@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOnSignal swaps in a mux freshly built by build every time the process receives SIGHUP.
+func (h *ReloadableHandler) ReloadOnSignal(build func() *http.ServeMux) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			h.Reload(build())
+		}
+	}()
+}
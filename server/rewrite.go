@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Rule rewrites paths matching Match to Replace, which can refer to capture groups as $1, ${name}...
+// (see regexp.Regexp.Expand). Anchor Match with ^ and $ unless a partial replacement is intended.
+type Rule struct {
+	Match   *regexp.Regexp
+	Replace string
+}
+
+// RewriteMiddleware rewrites r.URL.Path with the first matching rule before next (usually the mux) sees it,
+// so legacy URLs can be served by the current handlers without redirecting the client.
+func RewriteMiddleware(rules []Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range rules {
+				if !rule.Match.MatchString(r.URL.Path) {
+					continue
+				}
+				r2 := r.Clone(r.Context()) // [1]
+				r2.URL.Path = rule.Match.ReplaceAllString(r.URL.Path, rule.Replace)
+				r2.URL.RawPath = "" // recomputed from Path, a stale RawPath would win otherwise
+				r = r2
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NormalizePathMiddleware trims stray whitespace off the path ("/posts%20" becomes "/posts") and,
+// with lowercase, lowercases it too, so "/Posts" finds the "/posts" route. It's opt-in on purpose:
+// the mux treats paths as case sensitive, and so do path values, "/user/AbC" hands the handler "abc". [2]
+// The path the client sent is kept in the context, see OriginalPath.
+func NormalizePathMiddleware(lowercase bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimSpace(r.URL.Path)
+			if lowercase {
+				path = strings.ToLower(path)
+			}
+			if path == r.URL.Path {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if path == "" {
+				path = "/"
+			}
+
+			r2 := r.Clone(context.WithValue(r.Context(), originalPathKey, r.URL.Path))
+			r2.URL.Path = path
+			r2.URL.RawPath = ""
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// OriginalPath returns the path before NormalizePathMiddleware changed it, or r.URL.Path if it didn't.
+func OriginalPath(r *http.Request) string {
+	if path, ok := r.Context().Value(originalPathKey).(string); ok {
+		return path
+	}
+	return r.URL.Path
+}
+
+/*
+[1] : Middlewares shouldn't modify the request they were given, whoever called them may still be using it
+			(the logging middleware reads r.URL.Path after the handler returns, and should log what the client asked for).
+
+[2] : Serving the same resource under many URLs has a cost beyond surprises: caches and analytics count
+			"/Posts" and "/posts" separately. A redirect to the canonical path is the stricter alternative.
+*/
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRewriteMiddleware(t *testing.T) {
+	rules := []Rule{
+		{Match: regexp.MustCompile(`^/blog/(\d+)$`), Replace: "/posts/$1"},
+		{Match: regexp.MustCompile(`^/u/(?P<name>[a-z]+)$`), Replace: "/user/${name}"},
+		{Match: regexp.MustCompile(`^/blog/.*$`), Replace: "/posts"}, // never reached for /blog/<digits>, the first match wins
+		{Match: regexp.MustCompile(`^/old/(.*)$`), Replace: "/new/$1"},
+	}
+	tests := []struct {
+		path     string
+		wantPath string
+	}{
+		{"/blog/42", "/posts/42"},
+		{"/u/amit", "/user/amit"},
+		{"/blog/latest", "/posts"},
+		{"/old/caf%C3%A9", "/new/café"},
+		{"/posts/1", "/posts/1"},
+		{"/blog", "/blog"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var seen string
+			h := RewriteMiddleware(rules)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.URL.Path
+			}))
+			r := httptest.NewRequest("GET", tt.path, nil)
+			before := r.URL.Path
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if seen != tt.wantPath {
+				t.Errorf("handler saw %q, want %q", seen, tt.wantPath)
+			}
+			if r.URL.Path != before {
+				t.Errorf("the caller's request was modified, its path is now %q", r.URL.Path)
+			}
+		})
+	}
+}
+
+func TestRewriteMiddlewareRouting(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /posts/{id}", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("post " + r.PathValue("id"))) })
+	h := RewriteMiddleware([]Rule{{Match: regexp.MustCompile(`^/blog/(\d+)$`), Replace: "/posts/$1"}})(mux)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/blog/7", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "post 7" {
+		t.Errorf("got %d %q, want the /posts/{id} route with id 7", w.Code, w.Body.String())
+	}
+}
+
+func TestNormalizePathMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		lowercase    bool
+		target       string
+		wantPath     string
+		wantOriginal string
+	}{
+		{"clean path", false, "/posts", "/posts", "/posts"},
+		{"trailing space", false, "/posts%20", "/posts", "/posts "},
+		{"tab and newline", false, "/posts%09%0A", "/posts", "/posts\t\n"},
+		{"case kept by default", false, "/Posts", "/Posts", "/Posts"},
+		{"lowercased", true, "/Posts/AbC", "/posts/abc", "/Posts/AbC"},
+		{"lowercased and trimmed", true, "/POSTS%20", "/posts", "/POSTS "},
+		{"only whitespace", false, "/%20", "/", "/ "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen, original string
+			h := NormalizePathMiddleware(tt.lowercase)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen, original = r.URL.Path, OriginalPath(r)
+			}))
+			r := httptest.NewRequest("GET", tt.target, nil)
+			before := r.URL.Path
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if seen != tt.wantPath || original != tt.wantOriginal {
+				t.Errorf("handler saw %q, original %q, want %q, original %q", seen, original, tt.wantPath, tt.wantOriginal)
+			}
+			if r.URL.Path != before {
+				t.Errorf("the caller's request was modified, its path is now %q", r.URL.Path)
+			}
+		})
+	}
+}
+
+func TestNormalizePathMiddlewareRouting(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /posts", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("posts")) })
+	tests := []struct {
+		lowercase  bool
+		target     string
+		wantStatus int
+	}{
+		{false, "/posts%20", http.StatusOK},
+		{false, "/Posts", http.StatusNotFound},
+		{true, "/Posts", http.StatusOK},
+		{true, "/POSTS%20", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		NormalizePathMiddleware(tt.lowercase)(mux).ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("lowercase %v, GET %s = %d, want %d", tt.lowercase, tt.target, w.Code, tt.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type Route struct {
+	Method  string `json:"method"` // "ANY" when the pattern doesn't restrict the method
+	Pattern string `json:"pattern"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// RouteRegistry registers routes on a ServeMux while keeping a list of them,
+// and serves that list as JSON, an at-a-glance map of the API.
+type RouteRegistry struct {
+	mux *http.ServeMux
+
+	// MaxBodyBytes caps request bodies per route, keyed by the exact pattern passed to Handle,
+	// e.g. "POST /upload". Routes not listed aren't limited here. Set it before registering the routes. [2]
+	MaxBodyBytes map[string]int64
+
+	mu     sync.RWMutex
+	routes []Route
+}
+
+func NewRouteRegistry(mux *http.ServeMux) *RouteRegistry {
+	return &RouteRegistry{mux: mux}
+}
+
+// Handle is mux.Handle, plus an optional one line summary for the route list.
+func (rr *RouteRegistry) Handle(pattern string, handler http.Handler, summary ...string) {
+	if limit, ok := rr.MaxBodyBytes[pattern]; ok {
+		handler = limitBody(limit, handler)
+	}
+	rr.mux.Handle(pattern, labelRoute(pattern, handler)) // panics on an invalid or conflicting pattern, before we record it
+
+	route := Route{Method: "ANY", Pattern: pattern, Summary: strings.Join(summary, " ")}
+	if method, path, ok := strings.Cut(pattern, " "); ok { // "GET /posts" [1]
+		route.Method, route.Pattern = method, strings.TrimSpace(path)
+	}
+
+	rr.mu.Lock()
+	rr.routes = append(rr.routes, route)
+	rr.mu.Unlock()
+}
+
+func (rr *RouteRegistry) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), summary ...string) {
+	rr.Handle(pattern, http.HandlerFunc(handler), summary...)
+}
+
+func (rr *RouteRegistry) Routes() []Route {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	return append([]Route(nil), rr.routes...)
+}
+
+// limitBody rejects bodies over limit bytes with a 413. A declared Content-Length is checked up front,
+// chunked bodies only fail once the handler has read past the limit.
+func limitBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			writeError(w, r, &statusError{
+				status: http.StatusRequestEntityTooLarge,
+				err:    fmt.Errorf("request body larger than %d bytes", limit),
+			})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rr *RouteRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rr.Routes())
+}
+
+/*
+[1] : Go 1.22 patterns are "[METHOD ][HOST]/[PATH]", the method, when present, is separated by a space.
+
+[2] : One limit for the whole server has to fit its largest upload, which leaves every tiny JSON endpoint
+			accepting megabytes too. Limiting per route, after the mux picked it, lets each route say what it expects.
+			A handler's own MaxBytesReader still applies on top, whichever limit is lower wins.
+*/
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRouteRegistry(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+	rr := NewRouteRegistry(http.NewServeMux())
+	rr.HandleFunc("/", ok, "home page")
+	rr.HandleFunc("GET /posts", ok, "list posts")
+	rr.HandleFunc("POST /posts/create", ok)
+	rr.HandleFunc("DELETE  /posts/{id}", ok, "delete", "a post")
+	rr.Handle("GET /routes", rr, "this list")
+
+	w := httptest.NewRecorder()
+	rr.mux.ServeHTTP(w, httptest.NewRequest("GET", "/routes", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /routes: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got []Route
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Route{
+		{Method: "ANY", Pattern: "/", Summary: "home page"},
+		{Method: "GET", Pattern: "/posts", Summary: "list posts"},
+		{Method: "POST", Pattern: "/posts/create"},
+		{Method: "DELETE", Pattern: "/posts/{id}", Summary: "delete a post"},
+		{Method: "GET", Pattern: "/routes", Summary: "this list"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	// and the routes themselves are registered on the mux
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/posts", http.StatusOK},
+		{"POST", "/posts/create", http.StatusOK},
+		{"DELETE", "/posts/7", http.StatusOK},
+		{"POST", "/posts", http.StatusOK}, // falls back to "/", which matches any method and path
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		rr.mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRouteRegistryMaxBodyBytes(t *testing.T) {
+	rr := NewRouteRegistry(http.NewServeMux())
+	rr.MaxBodyBytes = map[string]int64{"POST /login": 64, "POST /upload": 1 << 20}
+	read := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, &statusError{status: http.StatusRequestEntityTooLarge, err: err})
+			return
+		}
+		fmt.Fprintf(w, "read %d bytes", len(b))
+	}
+	rr.HandleFunc("POST /login", read)
+	rr.HandleFunc("POST /upload", read)
+	rr.HandleFunc("POST /unlisted", read)
+
+	tests := []struct {
+		name     string
+		path     string
+		size     int
+		chunked  bool // no Content-Length, the limit is only hit while reading
+		wantCode int
+	}{
+		{"login within its limit", "/login", 64, false, http.StatusOK},
+		{"login over its limit", "/login", 65, false, http.StatusRequestEntityTooLarge},
+		{"login over its limit, chunked", "/login", 65, true, http.StatusRequestEntityTooLarge},
+		{"upload takes a larger body", "/upload", 512 << 10, false, http.StatusOK},
+		{"upload over its limit", "/upload", 1<<20 + 1, false, http.StatusRequestEntityTooLarge},
+		{"unlisted routes aren't limited", "/unlisted", 2 << 20, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.path, strings.NewReader(strings.Repeat("a", tt.size)))
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			rr.mux.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if want := fmt.Sprintf("read %d bytes", tt.size); tt.wantCode == http.StatusOK && w.Body.String() != want {
+				t.Errorf("body = %q, want %q", w.Body.String(), want)
+			}
+		})
+	}
+}
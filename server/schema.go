@@ -0,0 +1,138 @@
+/*
+Declarative request body validation.
+
+Each route that takes JSON declares what its body must look like:
+	Schema{
+		"title": {Type: "string", Required: true, MaxLength: 120},
+		"stars": {Type: "integer", Min: Limit(1), Max: Limit(5)},
+	}
+ValidateJSONMiddleware checks the body against it before the handler runs, and answers a 422 listing
+every field that's wrong, not just the first, so a client can fix them all in one go.
+Fields the schema doesn't mention are let through.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"unicode/utf8"
+)
+
+// FieldRule describes one top level field of a JSON object body.
+type FieldRule struct {
+	Type      string   // "string", "number", "integer", "boolean", "object" or "array", empty accepts anything
+	Required  bool     // the field must be present (null counts as present)
+	Min, Max  *float64 // bounds for numbers, nil means unbounded
+	MaxLength int      // for strings, in characters, 0 means no limit
+}
+
+type Schema map[string]FieldRule
+
+// Limit is a shorthand for the *float64 bounds of a FieldRule.
+func Limit(v float64) *float64 { return &v }
+
+const maxValidatedBody = 1 << 20 // 1mb, the body is buffered in memory to validate it
+
+func ValidateJSONMiddleware(schema Schema) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidatedBody))
+			if err != nil {
+				writeError(w, r, &statusError{status: http.StatusRequestEntityTooLarge, err: fmt.Errorf("body larger than %d bytes", maxValidatedBody)})
+				return
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(buf, &body); err != nil || body == nil {
+				writeError(w, r, badRequest(fmt.Errorf("body must be a JSON object")))
+				return
+			}
+
+			if errs := schema.Validate(body); len(errs) > 0 {
+				writeError(w, r, errs)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(buf)) // the handler decodes the body itself, give it back unread
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Validate returns the problems with body, sorted by field name.
+func (s Schema) Validate(body map[string]any) ValidationErrors {
+	var errs ValidationErrors
+	for field, rule := range s {
+		value, ok := body[field]
+		if !ok {
+			if rule.Required {
+				errs = append(errs, FieldError{field, "required", "is required"})
+			}
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if code, msg := rule.check(value); code != "" {
+			errs = append(errs, FieldError{field, code, msg})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field }) // map order is random [1]
+	return errs
+}
+
+// check returns what's wrong with value as a FieldError code and message, or "" if nothing is.
+func (rule FieldRule) check(value any) (code, msg string) {
+	switch v := value.(type) {
+	case string:
+		if rule.Type != "" && rule.Type != "string" {
+			return "type", "must be " + article(rule.Type)
+		}
+		if rule.MaxLength > 0 && utf8.RuneCountInString(v) > rule.MaxLength {
+			return "max_length", fmt.Sprintf("must be at most %d characters", rule.MaxLength)
+		}
+	case float64: // encoding/json decodes every number into a float64
+		switch {
+		case rule.Type == "integer" && v != math.Trunc(v):
+			return "type", "must be an integer"
+		case rule.Type != "" && rule.Type != "number" && rule.Type != "integer":
+			return "type", "must be " + article(rule.Type)
+		case rule.Min != nil && v < *rule.Min:
+			return "min", fmt.Sprintf("must be at least %v", *rule.Min)
+		case rule.Max != nil && v > *rule.Max:
+			return "max", fmt.Sprintf("must be at most %v", *rule.Max)
+		}
+	case bool:
+		if rule.Type != "" && rule.Type != "boolean" {
+			return "type", "must be " + article(rule.Type)
+		}
+	case map[string]any:
+		if rule.Type != "" && rule.Type != "object" {
+			return "type", "must be " + article(rule.Type)
+		}
+	case []any:
+		if rule.Type != "" && rule.Type != "array" {
+			return "type", "must be " + article(rule.Type)
+		}
+	}
+	return "", ""
+}
+
+func article(typ string) string {
+	switch typ[0] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+/*
+[1] : Ranging over a map visits the keys in a different order every time, without sorting
+			the same bad body would list its errors differently from one request to the next.
+*/
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateJSONMiddleware(t *testing.T) {
+	schema := Schema{
+		"title": {Type: "string", Required: true, MaxLength: 5},
+		"stars": {Type: "integer", Min: Limit(1), Max: Limit(5)},
+	}
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantFields []FieldError
+	}{
+		{"valid", `{"title":"hello","stars":3}`, http.StatusOK, nil},
+		{"unknown fields pass", `{"title":"hello","extra":true}`, http.StatusOK, nil},
+		{"null counts as present", `{"title":null}`, http.StatusOK, nil},
+		{"missing required and wrong type", `{"stars":"five"}`, http.StatusUnprocessableEntity, []FieldError{
+			{"stars", "type", "must be an integer"},
+			{"title", "required", "is required"},
+		}},
+		{"not an integer", `{"title":"hi","stars":2.5}`, http.StatusUnprocessableEntity, []FieldError{
+			{"stars", "type", "must be an integer"},
+		}},
+		{"out of range", `{"title":"hi","stars":0}`, http.StatusUnprocessableEntity, []FieldError{
+			{"stars", "min", "must be at least 1"},
+		}},
+		{"too long", `{"title":"héllo!","stars":6}`, http.StatusUnprocessableEntity, []FieldError{
+			{"stars", "max", "must be at most 5"},
+			{"title", "max_length", "must be at most 5 characters"},
+		}},
+		{"not an object", `["title"]`, http.StatusBadRequest, nil},
+		{"not json", `title=hello`, http.StatusBadRequest, nil},
+		{"null body", `null`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handlerBody string
+			h := ValidateJSONMiddleware(schema)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				handlerBody = string(b)
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/posts/create", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantCode == http.StatusOK {
+				if handlerBody != tt.body {
+					t.Errorf("handler read %q, want the body untouched", handlerBody)
+				}
+				return
+			}
+			var res errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.Fields, tt.wantFields) {
+				t.Errorf("fields = %+v, want %+v", res.Fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestFieldRuleTypes(t *testing.T) {
+	tests := []struct {
+		typ, value string
+		wantMsg    string
+	}{
+		{"string", `"a"`, ""},
+		{"string", `1`, "must be a string"},
+		{"number", `1.5`, ""},
+		{"number", `true`, "must be a number"},
+		{"boolean", `false`, ""},
+		{"boolean", `"false"`, "must be a boolean"},
+		{"object", `{}`, ""},
+		{"object", `[]`, "must be an object"},
+		{"array", `[]`, ""},
+		{"array", `{}`, "must be an array"},
+		{"", `{}`, ""},
+	}
+	for _, tt := range tests {
+		var v any
+		if err := json.Unmarshal([]byte(tt.value), &v); err != nil {
+			t.Fatal(err)
+		}
+		if _, msg := (FieldRule{Type: tt.typ}).check(v); msg != tt.wantMsg {
+			t.Errorf("%q rule on %s = %q, want %q", tt.typ, tt.value, msg, tt.wantMsg)
+		}
+	}
+}
+
+func TestValidateJSONMiddlewareTooLarge(t *testing.T) {
+	h := ValidateJSONMiddleware(Schema{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called for an oversized body")
+	}))
+	big := `{"title":"` + strings.Repeat("a", maxValidatedBody) + `"}`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(big)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
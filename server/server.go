@@ -60,11 +60,21 @@ and is stored in the http.DefaultServeMux global variable.
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
 )
 
 type home struct{}
@@ -76,16 +86,23 @@ func (h home) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The page only changes when its template does, so let browsers keep their copy until then.
+	if info, err := os.Stat("templates/index.html"); err == nil && CheckNotModified(w, r, info.ModTime()) {
+		return
+	}
+
+	stop := StartTiming(r.Context(), "parse") // shows up in the browser devtools, see servertiming.go
 	templ, err := template.ParseFiles("templates/index.html")
+	stop()
 	if err != nil {
-		log.Print(err.Error())
+		logger.FromContext(r.Context()).Error("parsing template", "err", err)
 		http.Error(w, "Error Parsing Template", http.StatusInternalServerError)
 		return
 	}
 
 	err = templ.Execute(w, nil)
 	if err != nil {
-		log.Print(err.Error())
+		logger.FromContext(r.Context()).Error("executing template", "err", err)
 		http.Error(w, "Error Executing Template", http.StatusInternalServerError)
 		return
 	}
@@ -93,28 +110,48 @@ func (h home) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func handleUserById(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		w.Header().Set("Allow", "GET")
-		http.Error(w, "Method not Allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r, "GET")
+		return
+	}
+	id, err := PathInt(r, "id") // [1]*
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	id := r.PathValue("id") // [1]*
-	fmt.Fprintf(w, "Hello user %s", id)
+	fmt.Fprintf(w, "Hello user %d", id)
 }
 
 func handlePostCreate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		w.Header().Set("Allow", "POST") // [1]
+		// w.Header().Set("Allow", "POST")
 		// w.WriteHeader(405)
 		// w.Write([]byte("Method not allowed"))
-		http.Error(w, "Method not Allowed", http.StatusMethodNotAllowed) // [2]
+		MethodNotAllowed(w, r, "POST") // [1] [2]
 		return
 	}
 	w.Write([]byte("You can create new posts here!"))
 }
 
+type newPostRequest struct {
+	Title string `json:"title"`
+}
+
+type post struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+var lastPostID atomic.Int64
+
+func createPost(ctx context.Context, req newPostRequest) (post, error) {
+	if req.Title == "" {
+		return post{}, badRequest(errors.New("title is required"))
+	}
+	return post{ID: int(lastPostID.Add(1)), Title: req.Title}, nil
+}
+
 func user(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json") // [4]
-	w.Write([]byte(`{"name":"Amit"}`))
+	WriteEnvelope(w, http.StatusOK, map[string]string{"name": "Amit"}, nil) // [4]
 }
 
 func handleUserByQuery(w http.ResponseWriter, r *http.Request) {
@@ -126,30 +163,130 @@ func handleUserByQuery(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "User id from query: %d", id)
 }
 
-func main() {
+// The one route taking large bodies, it gets its own limit and its own deadline instead of the server's timeouts.
+const (
+	maxUploadBytes = 1 << 30          // 1 GiB
+	uploadTimeout  = 10 * time.Minute // enough for maxUploadBytes at ~2 MB/s
+)
+
+// newMux builds the routes, main calls it again to reload them (see reload.go).
+func newMux(degraded *DegradedMode, metrics *Metrics, debug bool) *http.ServeMux {
 	mux := http.NewServeMux()
+	routes := NewRouteRegistry(mux) // [6]
+	routes.MaxBodyBytes = map[string]int64{
+		"POST /posts/create": 16 << 10, // a single post is a title and a body
+		"POST /posts/batch":  1 << 20,
+	}
 
 	// method 1 :
-	mux.Handle("/", home{})
+	routes.Handle("/", RecoverMiddleware(HTMLPanicMapper)(degraded.Expensive(home{})), "home page")
 
 	// method 2 :
-	mux.Handle("/user", http.HandlerFunc(user))  // [3]
-	mux.HandleFunc("/user/{id}", handleUserById) // [2]*
-	mux.HandleFunc("GET /user/view", handleUserByQuery)
+	routes.Handle("/user", FieldsMiddleware(RecoverMiddleware(JSONPanicMapper)(http.HandlerFunc(user))), "current user") // [3]
+	routes.HandleFunc("/user/{id}", handleUserById, "user by id")                                                        // [2]*
+	routes.HandleFunc("GET /user/view", handleUserByQuery, "user by ?id= query")
 
 	// method 3 :
-	mux.HandleFunc("GET /posts", func(w http.ResponseWriter, r *http.Request) { // [3]*
+	routes.Handle("GET /posts", CacheMiddleware(5*time.Second, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { // [3]*
 		w.Write([]byte("Your posts were here..."))
-	})
+	})), "list posts, cached for 5s")
+
+	newPost := ValidateJSONMiddleware(Schema{
+		"title": {Type: "string", Required: true, MaxLength: 120},
+		"body":  {Type: "string"},
+	})(http.HandlerFunc(handlePostCreate))
+	routes.Handle("POST /posts/create", IdempotencyMiddleware(24*time.Hour)(newPost), "create a post")
+	routes.Handle("POST /posts/batch", FieldsMiddleware(BatchHandler(http.StatusCreated, createPost)), "create many posts at once")
+
+	gate := FeatureGateMiddleware(StaticFlags{ForUsers: map[string][]string{"drafts": {"amit"}}})
+	routes.Handle("GET /posts/drafts", gate("drafts")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Your drafts, " + Principal(r.Context())))
+	})), "unpublished posts, behind the drafts flag, try X-API-Key: demo-key-amit")
+	routes.Handle("POST /upload", UploadHandler(os.TempDir(), maxUploadBytes, uploadTimeout), "multipart file upload, streamed to disk")
+	routes.HandleFunc("GET /slow", handleSlow, "slow work that stops when the client disconnects, ?steps=N")
+	// ?fields= only on the JSON endpoints, FieldsMiddleware buffers the whole response (see fields.go)
+	routes.Handle("GET /routes", FieldsMiddleware(routes), "this list")
+	routes.Handle("GET /metrics", FieldsMiddleware(metrics), "request counters and per route latency buckets")
+	if debug {
+		MountPProf(mux, "/debug/pprof/") // straight on the mux, kept out of the public route list
+	}
 
-	mux.HandleFunc("POST /posts/create", handlePostCreate)
+	return mux
+}
 
-	server := http.Server{
-		Addr:    ":3000",
-		Handler: mux,
+func main() {
+	debug := flag.Bool("debug", false, "mount the pprof profiling endpoints under /debug/pprof/")
+	normalizePaths := flag.Bool("normalize-paths", false, "trim whitespace off request paths and lowercase them before routing")
+	flag.Parse()
+
+	accessLog := NewAccessLogger(os.Stdout, true) // the server's own messages go there too, one stream to search
+
+	degraded := &DegradedMode{Logger: accessLog}
+	degraded.ToggleOnSignal()
+
+	metrics := NewMetrics()
+	mux := NewReloadableHandler(newMux(degraded, metrics, *debug))
+	mux.Logger = accessLog
+	mux.ReloadOnSignal(func() *http.ServeMux { return newMux(degraded, metrics, *debug) })
+
+	var handler http.Handler = mux
+	handler = RewriteMiddleware([]Rule{
+		{Match: regexp.MustCompile(`^/users/(\d+)$`), Replace: "/user/$1"}, // the old plural route
+	})(handler)
+	if *normalizePaths {
+		handler = NormalizePathMiddleware(true)(handler) // outside the rewrite, so its rules see the normalized path
+	}
+	handler = RecoverMiddleware(nil)(handler) // catch-all for routes without their own mapper
+	handler = BodyLogMiddleware([]string{"password", "token"})(handler)
+	handler = DecompressRequestMiddleware(handler)
+	handler = ServerTimingMiddleware(handler)
+	handler = CompressMiddleware(handler)
+	handler = AcceptEncodingMiddleware(handler)
+	handler = ProtocolMiddleware(handler)
+	handler = SlowRequestMiddleware(500*time.Millisecond, accessLog)(handler)
+	handler = SampledLoggingMiddleware(accessLog, 50)(handler)
+	handler = GeoMiddleware(StubGeoLookup, 50*time.Millisecond)(handler)
+	handler = QueryLimitMiddleware(2048, 50)(handler)
+	handler = PathDepthMiddleware(16)(handler)
+	handler = RateLimitMiddleware(100, time.Minute)(handler)
+	handler = PrincipalFromAPIKeyMiddleware(APIKeys)(handler) // outside the rate limit and the gates, they want to know who's asking
+	bots := &BotFilter{Deny: BadBotPatterns, AllowEmptyUA: []string{"/metrics"}, Logger: accessLog}
+	handler = bots.Middleware(handler)
+	handler = ConcurrencyLimitMiddleware(256)(handler)
+	handler = degraded.Middleware(handler)
+	handler = HostAllowlistMiddleware([]string{"localhost", "127.0.0.1", "::1"})(handler)
+	handler = metrics.Middleware(handler)
+	handler = ConflictingLengthMiddleware(handler)
+	handler = LoggerMiddleware(accessLog)(handler) // for writeError, RecoverMiddleware and the rest logging from a request
+	handler = RequestIDMiddleware(handler)         // outermost, so everything below can see the id
+
+	server, err := NewServer(":3000", handler)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.ErrorLog = logger.NewStdLog(accessLog) // net/http's own complaints (TLS handshakes, panics...) go to the same place
+
+	// Only takes effect once we serve TLS, ListenAndServe below is plaintext HTTP/1.1.
+	EnableHTTP2(server)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		accessLog.Info("server listening on http://localhost:3000")
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	accessLog.Info("shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil { // stops accepting, waits for the requests in flight
+		accessLog.Error("shutdown", "err", err)
 	}
-	log.Print("server listening on http://localhost:3000")
-	log.Fatal(server.ListenAndServe())
+	metrics.LogReport(accessLog)
 }
 
 /*
@@ -161,6 +298,8 @@ func main() {
 
 [2] : http.Error() is a lightweight helper function which takes a given message and status code,
 			then calls the w.WriteHeader() and w.Write() methods behind the scenes for us.
+			MethodNotAllowed (errors.go) works the same way, setting the Allow header first
+			and answering with a JSON error instead of plain text.
 
 [3] : HandlerFunc(f) is a Handler that calls the function f.
 
@@ -171,7 +310,8 @@ func main() {
 			The http.DetectContentType() function generally works quite well, but a common gotcha
 			for web developers is that it can’t distinguish JSON from plain text.
 			So, by default, JSON responses will be sent with a Content-Type: text/plain; charset=utf-8 header.
-			You can prevent this from happening by setting the correct header manually in your handler
+			You can prevent this from happening by setting the correct header manually in your handler,
+			which WriteEnvelope (envelope.go) does for us before writing the JSON.
 
 [5] : URL query parameter It retrieve the value of a given parameter from the URL query string,
 			which we can do using the r.URL.Query().Get() method.
@@ -180,9 +320,14 @@ func main() {
 			In above example we check that it contains a positive integer value "id".
 			We do this by trying to convert the string value to an integer with the strconv.Atoi() function.
 
+[6] : Instead of calling mux.Handle / mux.HandleFunc directly, routes are registered through a RouteRegistry (routes.go).
+			Its Handle and HandleFunc are the mux's with an extra summary, and it keeps a list of the routes
+			which GET /routes serves as JSON.
+
 [x]* : These are the features introduced in Go 1.22.
 			1. mux.HandleFunc("/user/{id}", handleUserById), here {id} is the wildcard entry.
 			2. id := r.PathValue("id") gives the value of wildcard with name id.
+				 Wildcards are always strings, PathInt (params.go) parses it and turns a missing or non-numeric id into a 400.
 			3. Method Matching, We can now explicitly mention the HTTP Method we want to allow for given patterns.
 				 Any other Method except the mentioned one will return a 404 NOT FOUND
 */
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestServerTimingMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string // regexp the whole header must match
+	}{
+		{"two segments", func(w http.ResponseWriter, r *http.Request) {
+			stop := StartTiming(r.Context(), "db")
+			time.Sleep(5 * time.Millisecond)
+			stop()
+			RecordTiming(r.Context(), "render", 1500*time.Microsecond)
+			w.Write([]byte("ok"))
+		}, `^db;dur=([5-9]|\d\d+)\.\d, render;dur=1\.5, total;dur=\d+\.\d$`},
+		{"explicit status", func(w http.ResponseWriter, r *http.Request) {
+			RecordTiming(r.Context(), "db", 2*time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+		}, `^db;dur=2\.0, total;dur=\d+\.\d$`},
+		{"nothing written", func(w http.ResponseWriter, r *http.Request) {}, `^total;dur=\d+\.\d$`},
+		{"recorded after the headers went out", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+			RecordTiming(r.Context(), "late", time.Millisecond)
+		}, `^total;dur=\d+\.\d$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(ServerTimingMiddleware(tt.handler))
+			defer srv.Close()
+			res, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if got := res.Header.Values("Server-Timing"); len(got) != 1 || !regexp.MustCompile(tt.want).MatchString(got[0]) {
+				t.Errorf("Server-Timing = %q, want it to match %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Without the middleware the helpers are no-ops, handlers don't need to check whether it's there.
+func TestRecordTimingWithoutMiddleware(t *testing.T) {
+	RecordTiming(context.Background(), "db", time.Millisecond)
+	StartTiming(context.Background(), "db")()
+}
@@ -0,0 +1,49 @@
+/*
+Noticing that the client is gone.
+
+net/http cancels r.Context() as soon as it sees the client's connection close: once the handler
+has consumed the request body the server keeps reading the connection in the background, and a read
+that hits EOF cancels the context. [1] A handler doing long work only has to look at the context
+between steps, instead of finishing the work for nobody:
+
+	curl "localhost:3000/slow?steps=20"   # then hit ctrl+c, the log says where the work stopped
+*/
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+const slowStep = 500 * time.Millisecond
+
+// handleSlow pretends to do ?steps= (default 10) half second chunks of work, giving up on the first
+// one that finds the request canceled.
+func handleSlow(w http.ResponseWriter, r *http.Request) {
+	steps, err := strconv.Atoi(r.URL.Query().Get("steps"))
+	if err != nil || steps <= 0 {
+		steps = 10
+	}
+	steps = min(steps, 50) // stay well inside the server's WriteTimeout
+
+	for i := range steps {
+		select {
+		case <-time.After(slowStep): // one chunk of "work"
+		case <-r.Context().Done():
+			logger.FromContext(r.Context()).Info("client gone, stopping", "request_id", RequestID(r.Context()),
+				"err", r.Context().Err(), "step", i, "steps", steps)
+			return // nobody is left to answer, writing would fail anyway
+		}
+	}
+	w.Write([]byte("done after " + strconv.Itoa(steps) + " steps\n"))
+}
+
+/*
+[1] : The background read only starts once the body has been read to the end, net/http can't read past an
+			unread body looking for EOF. A handler still reading a large upload learns about the disconnect from
+			its next body read failing instead. Over HTTP/2 the client canceling the stream (RST_STREAM) does the same.
+*/
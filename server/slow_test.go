@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+type chanWriter chan string
+
+func (c chanWriter) Write(b []byte) (int, error) {
+	c <- string(b)
+	return len(b), nil
+}
+
+// A client hanging up cancels the handler's context, which stops at its next step instead of doing all 20.
+func TestSlowStopsOnDisconnect(t *testing.T) {
+	logged := make(chanWriter, 1)
+	srv := httptest.NewServer(LoggerMiddleware(logger.New(logged, slog.LevelInfo))(http.HandlerFunc(handleSlow)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/slow?steps=20", nil)
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("request finished, want it cut short by the client's timeout")
+	}
+
+	select {
+	case line := <-logged:
+		if !strings.Contains(line, "client gone, stopping") || !strings.Contains(line, "step=0 steps=20") {
+			t.Errorf("logged %q", line)
+		}
+	case <-time.After(2 * slowStep):
+		t.Fatal("handler didn't notice the client was gone")
+	}
+}
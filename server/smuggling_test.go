@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConflictingLengthMiddleware(t *testing.T) {
+	tests := []struct {
+		name             string
+		header           http.Header
+		transferEncoding []string // r.TransferEncoding, where net/http moves the header once it parsed it
+		wantStatus       int
+	}{
+		{"no body", http.Header{}, nil, http.StatusOK},
+		{"one Content-Length", http.Header{"Content-Length": {"5"}}, nil, http.StatusOK},
+		{"chunked", http.Header{}, []string{"chunked"}, http.StatusOK},
+		{"two Content-Lengths", http.Header{"Content-Length": {"5", "6"}}, nil, http.StatusBadRequest},
+		{"two equal Content-Lengths", http.Header{"Content-Length": {"5", "5"}}, nil, http.StatusBadRequest},
+		{"comma separated Content-Lengths", http.Header{"Content-Length": {"5, 5"}}, nil, http.StatusBadRequest},
+		{"Content-Length and Transfer-Encoding header", http.Header{"Content-Length": {"5"}, "Transfer-Encoding": {"chunked"}}, nil, http.StatusBadRequest},
+		{"Content-Length and parsed Transfer-Encoding", http.Header{"Content-Length": {"5"}}, []string{"chunked"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := ConflictingLengthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+			r := httptest.NewRequest("POST", "/posts/create", nil)
+			r.Header = tt.header
+			r.TransferEncoding = tt.transferEncoding
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus || called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("status = %d, handler called %v, want %d", w.Code, called, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Serving a Single Page Application.
+
+The front-end router owns URLs like /dashboard or /users/42, there is no such file on disk:
+the server has to answer them with index.html and let the JS pick the page. Typos in asset URLs
+(/app.jss) should still be a 404 though, handing index.html to a <script> tag only produces a confusing
+syntax error in the browser. So anything with a file extension is looked up for real, without fallback.
+*/
+
+package main
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+)
+
+func SPAHandler(root, indexFile string) http.Handler {
+	dir := http.Dir(root) // [1]
+	files := http.FileServer(dir)
+	index := filepath.Join(root, indexFile)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := path.Clean("/" + r.URL.Path)
+
+		if f, err := dir.Open(p); err == nil {
+			info, err := f.Stat()
+			f.Close()
+			if err == nil && !info.IsDir() {
+				files.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if path.Ext(p) != "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache") // index.html points at the current asset hashes, always revalidate it
+		http.ServeFile(w, r, index)
+	})
+}
+
+/*
+[1] : http.Dir refuses to open anything outside root, "/../../etc/passwd" can't escape it.
+*/
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSPAHandler(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":      "<div id=app></div>",
+		"app.js":          "console.log(1)",
+		"assets/logo.svg": "<svg/>",
+		"docs/readme":     "no extension",
+	} {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path      string
+		wantCode  int
+		wantBody  string
+		wantIndex bool // index.html fallbacks are sent as no-cache
+	}{
+		{"/app.js", http.StatusOK, "console.log(1)", false},
+		{"/assets/logo.svg", http.StatusOK, "<svg/>", false},
+		{"/docs/readme", http.StatusOK, "no extension", false},
+		{"/", http.StatusOK, "<div id=app></div>", true},
+		{"/dashboard", http.StatusOK, "<div id=app></div>", true},
+		{"/users/42", http.StatusOK, "<div id=app></div>", true},
+		{"/assets", http.StatusOK, "<div id=app></div>", true}, // a directory isn't a page
+		{"/missing.js", http.StatusNotFound, "", false},
+		{"/assets/missing.png", http.StatusNotFound, "", false},
+		{"/../../etc/passwd", http.StatusBadRequest, "", true}, // http.ServeFile rejects any ".." in the URL
+	}
+	h := SPAHandler(root, "index.html")
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Cache-Control") == "no-cache"; got != tt.wantIndex {
+				t.Errorf("Cache-Control = %q, want no-cache only on the index fallback", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
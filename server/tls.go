@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+)
+
+// HTTPSRedirectMiddleware sends plaintext requests to the same URL over https on httpsPort.
+// A request counts as secure if it came in over TLS, or if the TLS terminating proxy in front of us
+// says so in X-Forwarded-Proto. [1]
+func HTTPSRedirectMiddleware(httpsPort string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requestScheme(r) == "https" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if httpsPort != "" && httpsPort != "443" {
+				host = net.JoinHostPort(host, httpsPort)
+			}
+
+			status := http.StatusMovedPermanently
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				status = http.StatusPermanentRedirect // [2]
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+		})
+	}
+}
+
+// EnableHTTP2 configures srv to offer HTTP/2 to TLS clients, falling back to HTTP/1.1 for the rest.
+// It keeps an existing TLSConfig (certificates and all) and only touches the protocol list and minimum version. [3]
+func EnableHTTP2(srv *http.Server) {
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{}
+	}
+	srv.TLSConfig.MinVersion = max(srv.TLSConfig.MinVersion, tls.VersionTLS12) // HTTP/2 forbids anything older
+	srv.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+}
+
+// ProtocolMiddleware reports the protocol the request came in over ("HTTP/1.1", "HTTP/2.0") in an X-Protocol header,
+// handy to check what a client actually negotiated.
+func ProtocolMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Protocol", r.Proto) // "HTTP/" + r.ProtoMajor + "." + r.ProtoMinor
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+[1] : Only trust X-Forwarded-Proto when a proxy you control sets it (and overwrites whatever the client sent),
+			any client can send the header itself.
+
+[2] : On a 301 clients are allowed to (and browsers do) turn a POST into a GET, dropping the body.
+			308 is the same permanent redirect but keeps the method and body.
+
+[3] : HTTP/2 is picked during the TLS handshake through ALPN, the client lists the protocols it speaks,
+			the server picks one from NextProtos. ListenAndServeTLS already adds "h2" unless TLSNextProto is set,
+			spelling it out means no surprises when a custom TLSConfig is plugged in.
+			Plain http:// never gets HTTP/2 from net/http, browsers don't do h2 without TLS anyway.
+*/
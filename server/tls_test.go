@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHTTPSRedirectMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		port           string
+		method, target string // NewRequest sets r.TLS for https:// targets
+		forwardedProto string
+		wantStatus     int
+		wantLocation   string
+	}{
+		{"plaintext", "8443", "GET", "http://example.com/posts?page=2", "", http.StatusMovedPermanently, "https://example.com:8443/posts?page=2"},
+		{"default port, host port dropped", "443", "GET", "http://example.com:8080/", "", http.StatusMovedPermanently, "https://example.com/"},
+		{"no port", "", "HEAD", "http://example.com/a", "", http.StatusMovedPermanently, "https://example.com/a"},
+		{"post keeps its method", "443", "POST", "http://example.com/posts/create", "", http.StatusPermanentRedirect, "https://example.com/posts/create"},
+		{"forwarded http", "443", "GET", "http://example.com/", "http", http.StatusMovedPermanently, "https://example.com/"},
+		{"tls", "443", "GET", "https://example.com/", "", http.StatusOK, ""},
+		{"behind a tls proxy", "443", "GET", "http://example.com/", "https", http.StatusOK, ""},
+		{"first forwarded entry counts", "443", "GET", "http://example.com/", "HTTPS, http", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := HTTPSRedirectMiddleware(tt.port)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.forwardedProto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus || w.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("got %d to %q, want %d to %q", w.Code, w.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestEnableHTTP2(t *testing.T) {
+	cert := tls.Certificate{Certificate: [][]byte{{1}}}
+	tests := []struct {
+		name           string
+		config         *tls.Config
+		wantMinVersion uint16
+		wantCerts      int
+	}{
+		{"no config", nil, tls.VersionTLS12, 0},
+		{"keeps certificates", &tls.Config{Certificates: []tls.Certificate{cert}}, tls.VersionTLS12, 1},
+		{"raises an old minimum", &tls.Config{MinVersion: tls.VersionTLS10}, tls.VersionTLS12, 0},
+		{"keeps a stricter minimum", &tls.Config{MinVersion: tls.VersionTLS13}, tls.VersionTLS13, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &http.Server{TLSConfig: tt.config}
+			EnableHTTP2(srv)
+			c := srv.TLSConfig
+			if !slices.Equal(c.NextProtos, []string{"h2", "http/1.1"}) {
+				t.Errorf("NextProtos = %q", c.NextProtos)
+			}
+			if c.MinVersion != tt.wantMinVersion || len(c.Certificates) != tt.wantCerts {
+				t.Errorf("MinVersion %x with %d certificates, want %x with %d", c.MinVersion, len(c.Certificates), tt.wantMinVersion, tt.wantCerts)
+			}
+		})
+	}
+}
+
+// Over TLS a client speaking h2 negotiates HTTP/2, one that only offers http/1.1 falls back to it.
+func TestProtocolMiddlewareOverTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(ProtocolMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	EnableHTTP2(srv.Config)
+	srv.TLS = srv.Config.TLSConfig
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	http1 := srv.Client().Transport.(*http.Transport).Clone()
+	http1.ForceAttemptHTTP2 = false
+	http1.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	http1.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{} // non nil turns off h2
+
+	tests := []struct {
+		name      string
+		client    *http.Client
+		wantProto string
+	}{
+		{"h2 client", srv.Client(), "HTTP/2.0"},
+		{"http/1.1 only client", &http.Client{Transport: http1}, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.client.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.Proto != tt.wantProto || res.Header.Get("X-Protocol") != tt.wantProto {
+				t.Errorf("response over %s reports X-Protocol %q, want %s", res.Proto, res.Header.Get("X-Protocol"), tt.wantProto)
+			}
+		})
+	}
+}
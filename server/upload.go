@@ -0,0 +1,134 @@
+/*
+Streaming multipart uploads.
+
+r.ParseMultipartForm(maxMemory) keeps parts up to maxMemory in RAM and spills the rest into temp files,
+but only once it has parsed the whole body, and it decides where the files go.
+StreamUpload reads the parts one by one with r.MultipartReader() instead and io.Copy's each file
+straight into dir, so memory stays at a copy buffer no matter how big the upload is.
+
+	curl -F "file=@big.iso" http://localhost:3000/upload
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+type UploadedFile struct {
+	Field    string `json:"field"`
+	Filename string `json:"filename"` // as sent by the client, never use it as a path [1]
+	Path     string `json:"-"`
+	Size     int64  `json:"size"`
+}
+
+// StreamUpload saves every file part of r's multipart body into dir, rejecting bodies over maxBytes
+// with a 413 for writeError. Non-file fields are skipped. If anything fails the files written so far are removed.
+func StreamUpload(w http.ResponseWriter, r *http.Request, dir string, maxBytes int64) (files []UploadedFile, err error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes) // counts the whole body, part headers and all
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				os.Remove(f.Path)
+			}
+			files = nil
+		}
+	}()
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, uploadError(err)
+		}
+		if part.FileName() == "" {
+			part.Close() // a plain form field, NextPart skips whatever we didn't read
+			continue
+		}
+
+		f, err := saveUploadPart(part, dir)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, f)
+	}
+}
+
+func saveUploadPart(part *multipart.Part, dir string) (uploaded UploadedFile, err error) {
+	dst, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return UploadedFile{}, err
+	}
+	defer func() {
+		if cerr := dst.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+
+	n, err := io.Copy(dst, part)
+	if err != nil {
+		return UploadedFile{}, uploadError(err)
+	}
+	return UploadedFile{Field: part.FormName(), Filename: filepath.Base(part.FileName()), Path: dst.Name(), Size: n}, nil
+}
+
+// uploadError gives a read error of the body the status it deserves.
+func uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &statusError{status: http.StatusRequestEntityTooLarge, err: fmt.Errorf("upload larger than %d bytes", tooLarge.Limit)}
+	}
+	return badRequest(err)
+}
+
+// UploadHandler serves StreamUpload, answering with the list of files it saved.
+// The server's Read/WriteTimeout are sized for ordinary requests, timeout (if > 0) replaces them
+// for an upload, it should be long enough to receive maxBytes over a slow link. [2]
+func UploadHandler(dir string, maxBytes int64, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if timeout > 0 {
+			rc := http.NewResponseController(w)
+			deadline := time.Now().Add(timeout)
+			if err := errors.Join(rc.SetReadDeadline(deadline), rc.SetWriteDeadline(deadline)); err != nil {
+				logger.FromContext(r.Context()).Warn("upload: can't extend the deadlines, the server timeouts still apply", "err", err)
+			}
+		}
+		files, err := StreamUpload(w, r, dir, maxBytes)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, files)
+	}
+}
+
+/*
+[1] : The filename is whatever the client put in the part's Content-Disposition, "../../etc/passwd" included.
+			The file is saved under a name we generate, filepath.Base only tidies up the one we report back.
+
+[2] : ReadTimeout counts from the moment the request starts arriving, so with the default 15s a 1 GiB upload
+			would need 70 MB/s to make it, anything slower is cut off halfway with a confusing read error.
+			Raising ReadTimeout for the whole server would give every slow client that long, the
+			ResponseController moves the deadline for this one request only. The write deadline moves too,
+			WriteTimeout also starts ticking when the headers are read and the answer comes after the body.
+			This needs every middleware's ResponseWriter wrapper to have an Unwrap method.
+*/
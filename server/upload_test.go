@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func multipartBody(t *testing.T, files map[string]string, fields map[string]string) (string, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	for filename, content := range files {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+	return mw.FormDataContentType(), &buf
+}
+
+func TestUploadHandler(t *testing.T) {
+	big := strings.Repeat("x", 64<<10) // well over the copy buffer, it has to go to disk in several writes
+
+	tests := []struct {
+		name         string
+		files        map[string]string
+		fields       map[string]string
+		notMultipart bool
+		wantStatus   int
+		wantSizes    map[string]int64 // reported filename -> size
+	}{
+		{"one big file", map[string]string{"big.bin": big}, nil, false, http.StatusCreated, map[string]int64{"big.bin": int64(len(big))}},
+		{"fields skipped", map[string]string{"a.txt": "hello"}, map[string]string{"note": "hi"}, false, http.StatusCreated, map[string]int64{"a.txt": 5}},
+		{"path in filename", map[string]string{"../../etc/passwd": "root"}, nil, false, http.StatusCreated, map[string]int64{"passwd": 4}},
+		{"over the limit", map[string]string{"huge.bin": big + big}, nil, false, http.StatusRequestEntityTooLarge, nil},
+		{"not multipart", nil, nil, true, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			contentType, body := multipartBody(t, tt.files, tt.fields)
+			if tt.notMultipart {
+				contentType = "application/json"
+			}
+			req := httptest.NewRequest("POST", "/upload", body)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			UploadHandler(dir, 100<<10, 0).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			onDisk, _ := os.ReadDir(dir)
+			if rec.Code != http.StatusCreated {
+				if len(onDisk) != 0 {
+					t.Errorf("%d files left in dir after a failed upload", len(onDisk))
+				}
+				return
+			}
+
+			var got []UploadedFile
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.wantSizes) || len(onDisk) != len(tt.wantSizes) {
+				t.Fatalf("saved %v (%d on disk), want %v", got, len(onDisk), tt.wantSizes)
+			}
+			var onDiskBytes, wantBytes int64
+			for _, entry := range onDisk {
+				info, _ := entry.Info()
+				onDiskBytes += info.Size()
+			}
+			for _, size := range tt.wantSizes {
+				wantBytes += size
+			}
+			if onDiskBytes != wantBytes {
+				t.Errorf("%d bytes on disk, want %d", onDiskBytes, wantBytes)
+			}
+			for _, f := range got {
+				if want, ok := tt.wantSizes[f.Filename]; !ok || f.Size != want {
+					t.Errorf("reported %s of %d bytes, want %v", f.Filename, f.Size, tt.wantSizes)
+				}
+			}
+		})
+	}
+}
+
+// An upload slower than the server's ReadTimeout still makes it, the handler moves the deadline.
+func TestUploadOutlivesReadTimeout(t *testing.T) {
+	srv := httptest.NewUnstartedServer(UploadHandler(t.TempDir(), 1<<20, 5*time.Second))
+	srv.Config.ReadTimeout = 200 * time.Millisecond
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	contentType, body := multipartBody(t, map[string]string{"slow.bin": strings.Repeat("y", 4096)}, nil)
+	pr, pw := io.Pipe()
+	go func() {
+		data, step := body.Bytes(), body.Len()/5+1
+		for len(data) > 0 { // trickle it over ~500ms
+			n := min(step, len(data))
+			time.Sleep(100 * time.Millisecond)
+			pw.Write(data[:n])
+			data = data[n:]
+		}
+		pw.Close()
+	}()
+
+	res, err := http.Post(srv.URL, contentType, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("status = %d: %s", res.StatusCode, b)
+	}
+}
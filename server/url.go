@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AbsoluteURL turns path (which may carry a query, "/posts?page=2") into an absolute URL for links
+// handed back to the client, e.g. a pagination "next". Behind a proxy the scheme and host the client
+// actually used come from X-Forwarded-Proto and X-Forwarded-Host, not from the request we received. [1]
+func AbsoluteURL(r *http.Request, path string) string {
+	scheme := requestScheme(r)
+	host := strings.ToLower(firstForwarded(r.Header.Get("X-Forwarded-Host")))
+	if host == "" {
+		host = strings.ToLower(r.Host)
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil &&
+		(scheme == "http" && port == "80" || scheme == "https" && port == "443") {
+		host = h // the default port is implied, leaving it out keeps one URL per resource
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6
+		}
+	}
+
+	base := &url.URL{Scheme: scheme, Host: host, Path: "/"}
+	ref, err := url.Parse(path)
+	if err != nil || ref.Scheme != "" || ref.Host != "" { // [3]
+		ref = &url.URL{Path: path}
+	}
+	return base.ResolveReference(ref).String() // also cleans up "." and ".." segments
+}
+
+// requestScheme is the scheme the client used to reach us, "http" or "https".
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if strings.EqualFold(firstForwarded(r.Header.Get("X-Forwarded-Proto")), "https") {
+		return "https"
+	}
+	return "http"
+}
+
+// firstForwarded returns the first entry of a forwarded header, each proxy on the way may append its own. [2]
+func firstForwarded(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+/*
+[1] : Same caveat as in tls.go, these headers are only trustworthy when a proxy we control sets them.
+
+[2] : "X-Forwarded-Proto: https, http" means the client spoke https to the first proxy,
+			which then spoke http to the next one. It's the client's view we want.
+
+[3] : "//evil.example/x" parses as a URL with a host, and resolving it would replace ours.
+			path is meant to be a path on this server, so anything that names another host is kept as a plain path.
+*/
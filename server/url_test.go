@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAbsoluteURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string // NewRequest sets r.TLS for https:// targets
+		headers map[string]string
+		path    string
+		want    string
+	}{
+		{"plaintext", "http://example.com/posts", nil, "/posts?page=2", "http://example.com/posts?page=2"},
+		{"tls", "https://example.com/", nil, "/posts", "https://example.com/posts"},
+		{"port kept", "http://localhost:3000/", nil, "/a", "http://localhost:3000/a"},
+		{"default port dropped", "https://Example.com:443/", nil, "/a", "https://example.com/a"},
+		{"ipv6 default port", "http://[::1]:80/", nil, "/a", "http://[::1]/a"},
+		{"behind a proxy", "http://10.0.0.5:8080/", map[string]string{
+			"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com",
+		}, "/posts?page=3", "https://api.example.com/posts?page=3"},
+		{"chain of proxies", "http://10.0.0.5:8080/", map[string]string{
+			"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "api.example.com, internal:8080",
+		}, "/", "https://api.example.com/"},
+		{"dot segments", "http://example.com/", nil, "/a/./b/../c", "http://example.com/a/c"},
+		{"relative path", "http://example.com/", nil, "posts", "http://example.com/posts"},
+		{"other host", "http://example.com/", nil, "//evil.example/x", "http://example.com//evil.example/x"},
+		{"other scheme", "http://example.com/", nil, "https://evil.example/x", "http://example.com/https://evil.example/x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := AbsoluteURL(r, tt.path); got != tt.want {
+				t.Errorf("AbsoluteURL(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MultiStore is a DB that writes every value to all of its backends.
+// A failing backend doesn't stop the others, all the errors come back joined together.
+// The id returned is the one the first backend (the primary) gave the value.
+type MultiStore struct {
+	Backends   []DB
+	Concurrent bool // write to all backends at once instead of one after the other
+}
+
+func (m *MultiStore) StoreToDB(ctx context.Context, value string) (string, error) {
+	ids := make([]string, len(m.Backends))
+	errs := make([]error, len(m.Backends))
+
+	if !m.Concurrent {
+		for i, db := range m.Backends {
+			ids[i], errs[i] = db.StoreToDB(ctx, value)
+		}
+		return primaryID(ids), errors.Join(errs...)
+	}
+
+	var wg sync.WaitGroup
+	for i, db := range m.Backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids[i], errs[i] = db.StoreToDB(ctx, value) // every goroutine owns its own slot, no lock needed
+		}()
+	}
+	wg.Wait()
+	return primaryID(ids), errors.Join(errs...) // nil errors are skipped, so this is nil when every backend succeeded
+}
+
+func primaryID(ids []string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
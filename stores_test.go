@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDB remembers what it was asked to store and answers with id and err.
+type recordingDB struct {
+	id  string
+	err error
+
+	mu     sync.Mutex
+	values []string
+}
+
+func (r *recordingDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	r.mu.Lock()
+	r.values = append(r.values, value)
+	r.mu.Unlock()
+	return r.id, r.err
+}
+
+func TestMultiStore(t *testing.T) {
+	errB := errors.New("b is down")
+	errC := errors.New("c is down")
+
+	tests := []struct {
+		name    string
+		errs    []error // one per backend
+		wantErr []error
+	}{
+		{"all succeed", []error{nil, nil, nil}, nil},
+		{"one fails", []error{nil, errB, nil}, []error{errB}},
+		{"two fail", []error{nil, errB, errC}, []error{errB, errC}},
+	}
+	for _, tt := range tests {
+		for _, concurrent := range []bool{false, true} {
+			name := tt.name
+			if concurrent {
+				name += ", concurrent"
+			}
+			t.Run(name, func(t *testing.T) {
+				var backends []DB
+				var dbs []*recordingDB
+				for i, err := range tt.errs {
+					db := &recordingDB{id: string(rune('a' + i)), err: err}
+					dbs = append(dbs, db)
+					backends = append(backends, db)
+				}
+				m := &MultiStore{Backends: backends, Concurrent: concurrent}
+
+				id, err := m.StoreToDB(context.Background(), "hello")
+				if id != "a" {
+					t.Errorf("id = %q, want the primary's %q", id, "a")
+				}
+				if (err == nil) != (len(tt.wantErr) == 0) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+				for _, want := range tt.wantErr {
+					if !errors.Is(err, want) {
+						t.Errorf("err = %v, doesn't include %v", err, want)
+					}
+				}
+				for i, db := range dbs { // even the ones after a failing backend
+					if len(db.values) != 1 || db.values[0] != "hello" {
+						t.Errorf("backend %d stored %q, want [hello]", i, db.values)
+					}
+				}
+			})
+		}
+	}
+}
+
+// barrierDB only returns once all the backends sharing started have been called,
+// which never happens if they're called one after the other.
+type barrierDB struct {
+	started *sync.WaitGroup
+}
+
+func (b barrierDB) StoreToDB(ctx context.Context, value string) (string, error) {
+	b.started.Done()
+	done := make(chan struct{})
+	go func() { b.started.Wait(); close(done) }()
+	select {
+	case <-done:
+		return value, nil
+	case <-time.After(time.Second):
+		return "", errors.New("the other backends weren't called meanwhile")
+	}
+}
+
+func TestMultiStoreConcurrent(t *testing.T) {
+	var started sync.WaitGroup
+	started.Add(3)
+	m := &MultiStore{Backends: []DB{barrierDB{&started}, barrierDB{&started}, barrierDB{&started}}, Concurrent: true}
+	if _, err := m.StoreToDB(context.Background(), "hello"); err != nil {
+		t.Errorf("backends didn't run at the same time: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runBenchmark opens n concurrent short-lived connections against an in-process server,
+// reports the throughput and fails if goroutines are left behind once the server (and pool, if any) is shut down.
+//
+//	go run ./tcp-server -bench 500 -delay 10ms -workers 16
+func runBenchmark(n int, pool *Pool) error {
+	before := runtime.NumGoroutine()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	srv := &Server{Pool: pool}
+	go srv.Serve(l)
+
+	begin := time.Now()
+	completed := sendRequests(l.Addr().String(), n)
+	elapsed := time.Since(begin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if pool != nil {
+		if err := pool.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond) // [1]
+	after := runtime.NumGoroutine()
+
+	fmt.Printf("%d/%d requests in %v (%.0f req/s)\n", completed, n, elapsed, float64(completed)/elapsed.Seconds())
+	fmt.Printf("goroutines: %d before, %d after\n", before, after)
+	if after > before {
+		return fmt.Errorf("goroutine leak: %d goroutines still running", after-before)
+	}
+	return nil
+}
+
+// sendRequests makes n concurrent GET / requests to addr, one connection each,
+// and returns how many got a 200 back.
+func sendRequests(addr string, n int) int64 {
+	var wg sync.WaitGroup
+	var completed atomic.Int64
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+			res, _ := io.ReadAll(conn) // the server closes the connection after responding
+			if strings.HasPrefix(string(res), "HTTP/1.1 200") {
+				completed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return completed.Load()
+}
+
+/*
+[1] : Goroutines don't disappear the instant they return, give the runtime a moment to settle
+			before comparing runtime.NumGoroutine() with the count we started with.
+*/
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// noDelay drops do's fake processing delay for the duration of a test.
+func noDelay(tb testing.TB) {
+	old := fakeDelay
+	fakeDelay = 0
+	tb.Cleanup(func() { fakeDelay = old })
+}
+
+func BenchmarkServer(b *testing.B) {
+	noDelay(b)
+	for _, bb := range []struct {
+		name string
+		pool func() *Pool
+	}{
+		{"goroutine per conn", func() *Pool { return nil }},
+		{"pool of 16", func() *Pool { return NewPool(16, 64) }},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			srv := &Server{Pool: bb.pool(), Logger: logger.Nop}
+			addr := startServer(b, srv)
+			b.ResetTimer()
+			for range b.N {
+				if got := sendRequests(addr, 1); got != 1 {
+					b.Fatal("request failed")
+				}
+			}
+		})
+	}
+}
+
+// Every goroutine the server starts, per connection or in the pool, is gone once both are shut down.
+func TestServerLeavesNoGoroutines(t *testing.T) {
+	noDelay(t)
+	tests := []struct {
+		name string
+		pool func() *Pool
+	}{
+		{"goroutine per conn", func() *Pool { return nil }},
+		{"pool", func() *Pool { return NewPool(4, 16) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := &Server{Pool: tt.pool(), Logger: logger.Nop}
+			go srv.Serve(l)
+
+			if got := sendRequests(l.Addr().String(), 50); got != 50 {
+				t.Fatalf("%d of 50 requests got a 200", got)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if srv.Pool != nil {
+				if err := srv.Pool.Shutdown(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond) // see bench.go's [1]
+			}
+			if after := runtime.NumGoroutine(); after > before {
+				buf := make([]byte, 1<<16)
+				t.Fatalf("%d goroutines before, %d after:\n%s", before, after, buf[:runtime.Stack(buf, true)])
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+var ErrByteBudgetExceeded = errors.New("tcp-server: connection byte budget exceeded")
+
+// budgetConn fails every Read once the client has sent more than its budget over the whole connection,
+// however many requests (or RESP commands) that's spread over.
+type budgetConn struct {
+	net.Conn
+	remaining int64
+}
+
+func (c *budgetConn) Read(b []byte) (int, error) {
+	if c.remaining < 0 {
+		return 0, ErrByteBudgetExceeded
+	}
+	if int64(len(b)) > c.remaining+1 {
+		b = b[:c.remaining+1] // one byte past the budget is enough to know it's been exceeded
+	}
+
+	n, err := c.Conn.Read(b)
+	c.remaining -= int64(n)
+	if c.remaining < 0 {
+		return 0, ErrByteBudgetExceeded
+	}
+	return n, err
+}
+
+// drain reads and throws away, for a moment, what the client sent past its budget,
+// so that closing the connection right after the 413 doesn't destroy it on its way. [1]
+func (c *budgetConn) drain() {
+	c.Conn.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+	io.CopyN(io.Discard, c.Conn, 256<<10) // bounded, a client may well keep sending
+}
+
+/*
+[1] : Closing a socket that still has unread bytes in its receive buffer makes the kernel send a RST instead of a FIN,
+			and a RST discards whatever the client hadn't read yet, our 413 included: it gets "connection reset by peer".
+			net/http does the same dance when it rejects a request whose body it didn't read.
+*/
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBudgetConn(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  int64
+		writes  []string
+		wantErr bool
+	}{
+		{"under budget", 10, []string{"hello"}, false},
+		{"exactly the budget", 10, []string{"hello", "world"}, false},
+		{"one write past it", 10, []string{"hello world!"}, true},
+		{"spread over writes", 10, []string{"hello", "wor", "ld!"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			go func() {
+				for _, w := range tt.writes {
+					client.Write([]byte(w))
+				}
+				client.Close()
+			}()
+
+			_, err := io.ReadAll(&budgetConn{Conn: server, remaining: tt.budget})
+			if got := errors.Is(err, ErrByteBudgetExceeded); got != tt.wantErr {
+				t.Errorf("err = %v, want budget exceeded: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxConnBytesAnswers413(t *testing.T) {
+	noDelay(t)
+	addr := startServer(t, &Server{MaxConnBytes: 100})
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"small request", "GET / HTTP/1.1\r\nHost: x\r\n\r\n", "HTTP/1.1 200"},
+		{"huge request", "POST / HTTP/1.1\r\nHost: x\r\n\r\n" + strings.Repeat("x", 1000), "HTTP/1.1 413"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+			conn.Write([]byte(tt.request))
+
+			res, err := io.ReadAll(conn) // the server closes the connection after responding
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(res), tt.want) {
+				t.Errorf("got %q, want %s...", res, tt.want)
+			}
+		})
+	}
+}
+
+// The keep-alive loop answers 413 too, once the connection as a whole went past the budget.
+func TestMaxConnBytesKeepAlive(t *testing.T) {
+	addr := startServer(t, &Server{MaxConnBytes: 200, Handler: ServeJSON})
+	small := "GET / HTTP/1.1\r\nHost: x\r\n\r\n" // 27 bytes
+
+	tests := []struct {
+		name     string
+		requests []string // each one written after the previous response was read
+		want     []int
+	}{
+		{"under budget", []string{small, small, small}, []int{200, 200, 200}},
+		{"one huge request", []string{"GET / HTTP/1.1\r\nX-Pad: " + strings.Repeat("x", 1000) + "\r\n\r\n"}, []int{413}},
+		{"small requests adding up", []string{small, small, small, small, small, small, small, small}, []int{200, 200, 200, 200, 200, 200, 200, 413}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+			r := bufio.NewReader(conn)
+			var got []int
+			for _, req := range tt.requests {
+				conn.Write([]byte(req))
+				res, err := http.ReadResponse(r, nil)
+				if err != nil {
+					t.Fatalf("after %v: %v", got, err)
+				}
+				io.Copy(io.Discard, res.Body)
+				got = append(got, res.StatusCode)
+				if res.StatusCode == 413 && !res.Close {
+					t.Error("413 without Connection: close")
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("statuses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
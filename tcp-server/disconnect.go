@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+var errClientGone = errors.New("tcp-server: client disconnected")
+
+// watchDisconnect returns a context that's canceled, with errClientGone as its cause, when the client hangs up.
+// It's the raw socket version of what net/http does for r.Context(), and works the same way:
+// a background read that fails means the connection is gone. [1]
+// Only use it while the protocol has the client wait in silence (between reading a request and answering it),
+// any byte the client does send is swallowed. stop ends the watch, call it before reading from conn again.
+func watchDisconnect(ctx context.Context, conn net.Conn) (watched context.Context, stop func()) {
+	watched, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		var b [64]byte
+		for {
+			_, err := conn.Read(b[:]) // data only means the client is still there, keep waiting for the error [2]
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, os.ErrDeadlineExceeded) { // that one is stop (or a shutdown) expiring the deadline
+				cancel(errClientGone)
+			}
+			return
+		}
+	}()
+
+	return watched, func() {
+		conn.SetReadDeadline(time.Unix(1, 0)) // unblock the background read
+		<-done
+		if ctx.Err() == nil { // on shutdown the expired deadline is the server's, leave it be
+			conn.SetReadDeadline(time.Time{})
+		}
+		cancel(nil)
+	}
+}
+
+/*
+[1] : A client closing its end makes our read return io.EOF right away. One that crashed or lost its network
+			sends nothing at all, only TCP keepalive (see -keepalive) notices that, after a while, and fails the read then.
+
+[2] : A single read would return on the first byte a client pipelines behind its request, and the watch would
+			quietly end there: a hang up after that is never noticed. So we keep reading until the read fails.
+*/
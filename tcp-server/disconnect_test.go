@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWatchDisconnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   func(c net.Conn) // what the client does while the server waits, in its own goroutine
+		wantGone bool
+	}{
+		{"client hangs up", func(c net.Conn) { c.Close() }, true},
+		{"client sends more then hangs up", func(c net.Conn) {
+			c.Write([]byte("GET /next HTTP/1.1\r\n\r\n")) // a pipe write blocks until all of it is read
+			c.Close()
+		}, true},
+		{"client waits", func(c net.Conn) {}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+
+			ctx, stop := watchDisconnect(context.Background(), server)
+			go tt.client(client)
+
+			if !tt.wantGone {
+				select {
+				case <-ctx.Done():
+					t.Fatalf("context canceled with %v while the client was still there", context.Cause(ctx))
+				case <-time.After(50 * time.Millisecond):
+				}
+				stop()
+				if context.Cause(ctx) == errClientGone {
+					t.Error("stop canceled the context with errClientGone")
+				}
+				go client.Write([]byte("x")) // the connection is usable again after stop
+				server.SetReadDeadline(time.Now().Add(time.Second))
+				if _, err := server.Read(make([]byte, 1)); err != nil {
+					t.Errorf("read after stop: %v", err)
+				}
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(2 * time.Second):
+				t.Fatal("hang up not noticed")
+			}
+			if cause := context.Cause(ctx); cause != errClientGone {
+				t.Errorf("cause = %v, want errClientGone", cause)
+			}
+			stop()
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// dumpConn writes the bytes the client sends to w as a hex + ASCII dump, like hexdump -C, up to a byte budget:
+//
+//	00000000  47 45 54 20 2f 20 48 54  54 50 2f 31 2e 31 0d 0a  |GET / HTTP/1.1..|
+//
+// It dumps every Read as it happens, so what's shown is exactly what the handler is about to see.
+// The dump goes straight to a writer rather than through the Logger, which would escape its newlines.
+type dumpConn struct {
+	net.Conn
+	w         io.Writer
+	remaining int
+}
+
+var dumpMu sync.Mutex // keeps dumps of concurrent connections from interleaving line by line
+
+func (c *dumpConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.remaining > 0 {
+		shown := min(n, c.remaining)
+		c.remaining -= shown
+
+		dumpMu.Lock()
+		fmt.Fprintf(c.w, "%s sent %d bytes:\n%s", c.RemoteAddr(), n, hex.Dump(b[:shown]))
+		if c.remaining == 0 {
+			fmt.Fprintf(c.w, "%s: dump limit reached, the rest isn't shown\n", c.RemoteAddr())
+		}
+		dumpMu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumpBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		dumpBytes   int
+		want        []string
+		wantLimited bool
+	}{
+		{"whole request", 1024, []string{
+			"sent 35 bytes:\n",
+			"00000000  47 45 54 20 2f 20 48 54  54 50 2f 31 2e 31 0d 0a  |GET / HTTP/1.1..|\n",
+			"|Host: localhost.|\n",
+		}, false},
+		{"capped", 8, []string{
+			"sent 35 bytes:\n",
+			"00000000  47 45 54 20 2f 20 48 54                           |GET / HT|\n",
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			noDelay(t)
+			var dump syncBuffer
+			addr := startServer(t, &Server{DumpBytes: tt.dumpBytes, DumpTo: &dump})
+			if n := sendRequests(addr, 1); n != 1 {
+				t.Fatalf("%d requests answered, want 1", n)
+			}
+
+			got := string(dump.Bytes())
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("dump %q doesn't contain %q", got, want)
+				}
+			}
+			if limited := strings.Contains(got, "dump limit reached"); limited != tt.wantLimited {
+				t.Errorf("dump %q, want the limit note only when capped", got)
+			}
+			if tt.wantLimited && strings.Contains(got, "Host") {
+				t.Errorf("dump %q goes past the cap", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Just enough HTTP/1.1 to answer with a real JSON API over raw sockets.
+
+A request on the wire:
+	GET /hello HTTP/1.1\r\n          request line: method, target, version
+	Host: localhost:4221\r\n          headers, one per line
+	Content-Length: 0\r\n
+	\r\n                              an empty line ends the headers
+	...Content-Length bytes of body
+The response has the same shape with a status line instead ("HTTP/1.1 200 OK").
+Content-Length matters: without it (or chunked encoding) the client can only find the end of the
+body by waiting for the connection to close.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxHeaderLines = 100
+	maxBodyBytes   = 1 << 20
+)
+
+var ErrMalformedRequest = errors.New("tcp-server: malformed HTTP request")
+
+type Request struct {
+	Method  string
+	Path    string
+	Proto   string
+	Headers map[string]string // keys canonicalized, "content-type" is found as "Content-Type"
+	Body    []byte
+}
+
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// ReadRequest parses one HTTP/1.x request off r.
+func ReadRequest(r *bufio.Reader) (*Request, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(line, " ")
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/1.") {
+		return nil, ErrMalformedRequest
+	}
+	req := &Request{Method: parts[0], Path: parts[1], Proto: parts[2], Headers: make(map[string]string)}
+
+	for i := 0; ; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		if i == maxHeaderLines {
+			return nil, ErrMalformedRequest
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") { // "Host : x" is a classic smuggling trick
+			return nil, ErrMalformedRequest
+		}
+		req.Headers[textproto.CanonicalMIMEHeaderKey(name)] = strings.TrimSpace(value)
+	}
+
+	if cl, ok := req.Headers["Content-Length"]; ok {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 || n > maxBodyBytes {
+			return nil, ErrMalformedRequest
+		}
+		req.Body = make([]byte, n)
+		if _, err := io.ReadFull(r, req.Body); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// WriteTo writes resp as an HTTP/1.1 response, filling in Content-Length.
+func (resp *Response) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", resp.Status, statusText(resp.Status))
+
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, resp.Headers[name])
+	}
+	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(resp.Body))
+	b.Write(resp.Body)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func statusText(code int) string {
+	switch code {
+	case 200:
+		return "OK"
+	case 400:
+		return "Bad Request"
+	case 404:
+		return "Not Found"
+	case 405:
+		return "Method Not Allowed"
+	case 413:
+		return "Content Too Large"
+	case 500:
+		return "Internal Server Error"
+	}
+	return "Unknown"
+}
+
+func jsonResponse(status int, v any) *Response {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return &Response{Status: 500, Headers: map[string]string{"Content-Type": "text/plain"}, Body: []byte("Internal Server Error")}
+	}
+	return &Response{Status: status, Headers: map[string]string{"Content-Type": "application/json"}, Body: append(body, '\n')}
+}
+
+// handleJSON is the whole "API": GET anything, get a greeting back.
+func handleJSON(req *Request) *Response {
+	if req.Method != "GET" {
+		resp := jsonResponse(405, map[string]string{"error": "method not allowed"})
+		resp.Headers["Allow"] = "GET"
+		return resp
+	}
+	return jsonResponse(200, map[string]string{"message": "hello"})
+}
+
+// ServeJSON answers every request on conn with handleJSON, keeping the connection open between
+// requests unless the client asks for "Connection: close".
+func ServeJSON(ctx context.Context, conn net.Conn) {
+	serveRequests(ctx, conn, handleJSON)
+}
+
+// serveRequests is the keep-alive loop behind ServeJSON, answering each request with handle.
+func serveRequests(ctx context.Context, conn net.Conn, handle func(*Request) *Response) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for ctx.Err() == nil {
+		req, err := ReadRequest(r)
+		if errors.Is(err, ErrMalformedRequest) {
+			resp := jsonResponse(400, map[string]string{"error": "malformed request"})
+			resp.Headers["Connection"] = "close" // we can't tell where the bad request ends, so no next one
+			resp.WriteTo(conn)
+			return
+		}
+		if errors.Is(err, ErrByteBudgetExceeded) { // same answer as do() gives
+			resp := jsonResponse(413, map[string]string{"error": "connection byte budget exceeded"})
+			resp.Headers["Connection"] = "close"
+			resp.WriteTo(conn)
+			if bc, ok := conn.(*budgetConn); ok {
+				bc.drain()
+			}
+			return
+		}
+		if err != nil {
+			return // the client went away, or the server is shutting down
+		}
+
+		resp := handle(req)
+		closing := strings.EqualFold(req.Headers["Connection"], "close") || req.Proto == "HTTP/1.0"
+		if closing {
+			resp.Headers["Connection"] = "close"
+		}
+		if _, err := resp.WriteTo(conn); err != nil || closing {
+			return
+		}
+	}
+}
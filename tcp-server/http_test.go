@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    *Request
+		wantErr error
+	}{
+		{"get", "GET /hello HTTP/1.1\r\nHost: x\r\n\r\n",
+			&Request{Method: "GET", Path: "/hello", Proto: "HTTP/1.1", Headers: map[string]string{"Host": "x"}}, nil},
+		{"bare newlines", "GET / HTTP/1.0\ncontent-type:  text/plain \n\n",
+			&Request{Method: "GET", Path: "/", Proto: "HTTP/1.0", Headers: map[string]string{"Content-Type": "text/plain"}}, nil},
+		{"body", "POST /p HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello",
+			&Request{Method: "POST", Path: "/p", Proto: "HTTP/1.1", Headers: map[string]string{"Content-Length": "5"}, Body: []byte("hello")}, nil},
+		{"not http", "HELLO\r\n\r\n", nil, ErrMalformedRequest},
+		{"http/2", "GET / HTTP/2\r\n\r\n", nil, ErrMalformedRequest},
+		{"space before colon", "GET / HTTP/1.1\r\nHost : x\r\n\r\n", nil, ErrMalformedRequest},
+		{"no colon", "GET / HTTP/1.1\r\nHost\r\n\r\n", nil, ErrMalformedRequest},
+		{"bad content length", "POST / HTTP/1.1\r\nContent-Length: -1\r\n\r\n", nil, ErrMalformedRequest},
+		{"body too large", "POST / HTTP/1.1\r\nContent-Length: 2000000\r\n\r\n", nil, ErrMalformedRequest},
+		{"too many headers", "GET / HTTP/1.1\r\n" + strings.Repeat("X: y\r\n", maxHeaderLines+1) + "\r\n", nil, ErrMalformedRequest},
+		{"short body", "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhi", nil, io.ErrUnexpectedEOF},
+		{"cut off headers", "GET / HTTP/1.1\r\nHost: x\r\n", nil, io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ReadRequest(bufio.NewReader(strings.NewReader(tt.raw)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				return
+			}
+			if req.Method != tt.want.Method || req.Path != tt.want.Path || req.Proto != tt.want.Proto ||
+				string(req.Body) != string(tt.want.Body) || len(req.Headers) != len(tt.want.Headers) {
+				t.Fatalf("got %+v, want %+v", req, tt.want)
+			}
+			for k, v := range tt.want.Headers {
+				if req.Headers[k] != v {
+					t.Errorf("header %s = %q, want %q", k, req.Headers[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestServeJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantStatus int
+		wantBody   map[string]string
+		wantAllow  string
+		wantClose  bool // whether the server hangs up after answering
+	}{
+		{"get", "GET /hello HTTP/1.1\r\nHost: x\r\n\r\n", 200, map[string]string{"message": "hello"}, "", false},
+		{"connection close", "GET / HTTP/1.1\r\nConnection: close\r\n\r\n", 200, map[string]string{"message": "hello"}, "", true},
+		{"http/1.0", "GET / HTTP/1.0\r\n\r\n", 200, map[string]string{"message": "hello"}, "", true},
+		{"post", "POST / HTTP/1.1\r\nContent-Length: 2\r\n\r\n{}", 405, map[string]string{"error": "method not allowed"}, "GET", false},
+		{"malformed", "NOPE\r\n\r\n", 400, map[string]string{"error": "malformed request"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startServer(t, &Server{Handler: ServeJSON})
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(conn)
+
+			conn.Write([]byte(tt.raw))
+			res, err := http.ReadResponse(r, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if res.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", res.Header.Get("Content-Type"))
+			}
+			if len(body) != len(tt.wantBody) || body["message"] != tt.wantBody["message"] || body["error"] != tt.wantBody["error"] {
+				t.Errorf("body = %v, want %v", body, tt.wantBody)
+			}
+			if res.Header.Get("Allow") != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", res.Header.Get("Allow"), tt.wantAllow)
+			}
+			if res.Close != tt.wantClose {
+				t.Errorf("Connection: close = %v, want %v", res.Close, tt.wantClose)
+			}
+
+			// a kept alive connection answers the next request, a closed one is at EOF
+			conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+			res, err = http.ReadResponse(r, nil)
+			if tt.wantClose {
+				if err == nil {
+					t.Error("got a second response on a connection that should be closed")
+				}
+				return
+			}
+			if err != nil || res.StatusCode != 200 {
+				t.Fatalf("second request: %v, %v, want a 200 on the same connection", res, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Latency percentiles.
+
+An average hides the slow requests, percentiles show them: p99 = 250ms means 1 request in 100 took 250ms or more.
+Run the server with a fake delay and a small pool, then throw more connections at it than there are workers:
+p50 stays around the fake delay while p99 climbs, that's the time connections spent waiting in the queue.
+
+Only the last N samples are kept (a ring buffer), so memory stays fixed and the numbers follow recent traffic.
+*/
+
+package main
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+type LatencyRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int  // where the next sample goes
+	full    bool // whether we've wrapped around at least once
+}
+
+func NewLatencyRecorder(size int) *LatencyRecorder {
+	return &LatencyRecorder{samples: make([]time.Duration, size)}
+}
+
+func (l *LatencyRecorder) Record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples[l.next] = d
+	l.next++
+	if l.next == len(l.samples) {
+		l.next, l.full = 0, true // overwrite the oldest samples from now on
+	}
+}
+
+// Percentiles returns the given percentiles (0-100) of the recorded samples, using the nearest-rank method. [1]
+// All of them are 0 if nothing was recorded yet. Out of range ps are clamped: 150 is the max, -5 the min.
+func (l *LatencyRecorder) Percentiles(ps ...float64) []time.Duration {
+	l.mu.Lock()
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	sorted := slices.Clone(l.samples[:n])
+	l.mu.Unlock()
+
+	slices.Sort(sorted)
+
+	out := make([]time.Duration, len(ps))
+	if len(sorted) == 0 {
+		return out
+	}
+	for i, p := range ps {
+		rank := int(math.Ceil(min(max(p, 0), 100) / 100 * float64(len(sorted))))
+		out[i] = sorted[max(rank-1, 0)]
+	}
+	return out
+}
+
+// LogEvery logs p50/p90/p99 to lg every interval until ctx is done, a nil lg means logger.Default().
+func (l *LatencyRecorder) LogEvery(ctx context.Context, lg logger.Logger, interval time.Duration) {
+	lg = logger.Or(lg)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p := l.Percentiles(50, 90, 99)
+			lg.Info("latency", "p50", p[0], "p90", p[1], "p99", p[2])
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+/*
+[1] : Nearest rank: sort the n samples, the p-th percentile is the one at position ceil(p/100 * n) (1 based).
+			With samples 1..10, p90 is the 9th: 9. No interpolation, the result is always a latency we actually saw.
+*/
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatencyRecorderPercentiles(t *testing.T) {
+	ms := func(ns ...int) []time.Duration {
+		out := make([]time.Duration, len(ns))
+		for i, n := range ns {
+			out[i] = time.Duration(n) * time.Millisecond
+		}
+		return out
+	}
+	upTo := func(n int) []time.Duration { // 1ms..n ms, shuffled a bit so Percentiles has to sort
+		var out []time.Duration
+		for i := n; i >= 1; i -= 2 {
+			out = append(out, time.Duration(i)*time.Millisecond)
+		}
+		for i := n - 1; i >= 1; i -= 2 {
+			out = append(out, time.Duration(i)*time.Millisecond)
+		}
+		return out
+	}
+
+	inOrder := func(n int) []time.Duration {
+		var out []time.Duration
+		for i := 1; i <= n; i++ {
+			out = append(out, time.Duration(i)*time.Millisecond)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		size    int
+		samples []time.Duration
+		ps      []float64
+		want    []time.Duration
+	}{
+		{"nothing recorded", 10, nil, []float64{50, 99}, ms(0, 0)},
+		{"one sample", 10, ms(7), []float64{0, 50, 99, 100}, ms(7, 7, 7, 7)},
+		{"1 to 10", 10, upTo(10), []float64{50, 90, 99}, ms(5, 9, 10)},
+		{"1 to 100", 100, upTo(100), []float64{50, 90, 99}, ms(50, 90, 99)},
+		{"min and max", 100, upTo(100), []float64{0, 100}, ms(1, 100)},
+		{"partly filled", 100, upTo(20), []float64{50, 90, 99}, ms(10, 18, 20)},
+		{"wrapped", 10, inOrder(100), []float64{50, 90, 99}, ms(95, 99, 100)}, // only 91..100 are left
+		{"wrapped, newest kept", 10, append(ms(1000, 1000, 1000), upTo(10)...), []float64{50, 90, 100}, ms(5, 9, 10)},
+		{"out of range clamped", 10, upTo(10), []float64{-5, 150}, ms(1, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLatencyRecorder(tt.size)
+			for _, d := range tt.samples {
+				l.Record(d)
+			}
+			if got := l.Percentiles(tt.ps...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Percentiles(%v) = %v, want %v", tt.ps, got, tt.want)
+			}
+		})
+	}
+}
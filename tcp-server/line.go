@@ -0,0 +1,95 @@
+/*
+A line protocol: one command per line, one reply line per command.
+
+	PING         -> PONG
+	ECHO hello   -> hello
+	VERSION      -> build and uptime info
+	QUIT         -> BYE, then the server closes the connection
+Anything else gets "ERR unknown command". Easy to drive by hand:
+	nc localhost 4221    (after go run ./tcp-server -mode line)
+
+Closing is a handshake either way round. A client that's done sends QUIT and gets BYE.
+When the server shuts down it sends "BYE shutting down" first and gives the client a moment to QUIT,
+so a well behaved client learns the connection is going away instead of finding a dead socket on its next write.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const maxLineLen = 4 << 10 // 4kb, a longer line ends the connection
+
+type LineHandler struct {
+	CloseGrace time.Duration // how long to wait for QUIT after a shutdown BYE, 0 means 500ms
+}
+
+func (h *LineHandler) Serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 0, 512), maxLineLen)
+
+	for sc.Scan() { // keeps going until the read fails, which is also how a shutdown reaches us [1]
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(sc.Text()), " ") // TrimSpace also drops the \r of telnet style \r\n
+
+		var reply string
+		switch strings.ToUpper(cmd) {
+		case "":
+			continue
+		case "PING":
+			reply = "PONG"
+		case "ECHO":
+			reply = arg
+		case "VERSION":
+			reply = versionReply()
+		case "QUIT":
+			fmt.Fprint(conn, "BYE\n")
+			return
+		default:
+			reply = fmt.Sprintf("ERR unknown command %q", cmd)
+		}
+
+		if _, err := fmt.Fprint(conn, reply+"\n"); err != nil {
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		h.goodbye(conn)
+	}
+}
+
+// goodbye is the server's side of the closing handshake.
+func (h *LineHandler) goodbye(conn net.Conn) {
+	grace := h.CloseGrace
+	if grace == 0 {
+		grace = 500 * time.Millisecond
+	}
+
+	if _, err := fmt.Fprint(conn, "BYE shutting down\n"); err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Now().Add(grace)) // replaces the expired deadline that ended the read loop
+
+	sc := bufio.NewScanner(conn) // the old scanner is done for good once it has failed
+	sc.Buffer(make([]byte, 0, 512), maxLineLen)
+	for sc.Scan() {
+		if strings.EqualFold(strings.TrimSpace(sc.Text()), "QUIT") {
+			return
+		}
+		fmt.Fprint(conn, "ERR shutting down, send QUIT\n")
+	}
+}
+
+/*
+[1] : Checking ctx.Err() before each Scan would race with the server's context.AfterFunc, which expires the read deadline
+			on shutdown. We could see the cancellation first, extend the deadline in goodbye, and then have AfterFunc expire it
+			again under our feet. Waiting for the read to fail means the AfterFunc has already run.
+*/
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLineHandlerGoodbye(t *testing.T) {
+	const grace = 300 * time.Millisecond
+	tests := []struct {
+		name      string
+		send      string   // what the client answers the BYE with, "" for nothing
+		wantLines []string // what it gets after the BYE, before the connection closes
+		slow      bool     // whether the server should wait out the grace period
+	}{
+		{"client quits", "QUIT\n", nil, false},
+		{"client quits lowercase", "quit\r\n", nil, false},
+		{"client keeps talking", "PING\nQUIT\n", []string{"ERR shutting down, send QUIT\n"}, false},
+		{"client silent", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &Server{Handler: (&LineHandler{CloseGrace: grace}).Serve}
+			addr := startServer(t, srv)
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(conn)
+
+			conn.Write([]byte("PING\n")) // once PONG is back the handler is in its read loop
+			if line, err := r.ReadString('\n'); err != nil || line != "PONG\n" {
+				t.Fatalf("got %q, %v, want PONG", line, err)
+			}
+
+			shutdown := make(chan error, 1)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				shutdown <- srv.Shutdown(ctx)
+			}()
+
+			if line, err := r.ReadString('\n'); err != nil || line != "BYE shutting down\n" {
+				t.Fatalf("got %q, %v, want the BYE before the close", line, err)
+			}
+			begin := time.Now()
+			if tt.send != "" {
+				conn.Write([]byte(tt.send))
+			}
+			for _, want := range tt.wantLines {
+				if line, err := r.ReadString('\n'); err != nil || line != want {
+					t.Fatalf("got %q, %v, want %q", line, err, want)
+				}
+			}
+			if rest, err := r.ReadString('\n'); !errors.Is(err, io.EOF) {
+				t.Fatalf("got %q, %v, want the server to close the connection", rest, err)
+			}
+
+			closedAfter := time.Since(begin)
+			if tt.slow && closedAfter < grace*3/4 {
+				t.Errorf("closed after %v, want the %v grace period", closedAfter, grace)
+			}
+			if !tt.slow && closedAfter >= grace {
+				t.Errorf("closed after %v, want right after the QUIT", closedAfter)
+			}
+			if err := <-shutdown; err != nil {
+				t.Errorf("Shutdown = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// Listen binds addr on one stack ("tcp4" or "tcp6") or both ("tcp") and returns the address actually bound,
+// which is how we find out the port the OS picked for ":0".
+//
+// With "tcp" and no host (":4221") Go opens a single IPv6 socket that also accepts IPv4 clients
+// (they show up as ::ffff:1.2.3.4), falling back to IPv4 only on machines without IPv6. [1]
+func Listen(network, addr string) (net.Listener, *net.TCPAddr, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, nil, fmt.Errorf("listen: unsupported network %q, want tcp, tcp4 or tcp6", network)
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, l.Addr().(*net.TCPAddr), nil
+}
+
+/*
+[1] : Only "tcp" is dual-stack. Go sets IPV6_V6ONLY on every "tcp6" socket, so "tcp6" only talks IPv6
+			whatever it binds, "[::]:4221" included: an IPv4 client dialing it gets "connection refused".
+*/
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestListen(t *testing.T) {
+	tests := []struct {
+		network, addr string
+		dial          string // network and host to connect with
+		dialHost      string
+		wantIPv4      bool
+	}{
+		{"tcp4", "127.0.0.1:0", "tcp4", "127.0.0.1", true},
+		{"tcp4", ":0", "tcp4", "127.0.0.1", true},
+		{"tcp6", "[::1]:0", "tcp6", "::1", false},
+		{"tcp", "127.0.0.1:0", "tcp", "127.0.0.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network+" "+tt.addr, func(t *testing.T) {
+			l, addr, err := Listen(tt.network, tt.addr)
+			if err != nil {
+				if tt.network == "tcp6" {
+					t.Skipf("no IPv6 here: %v", err)
+				}
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			if addr.Port == 0 {
+				t.Fatalf("bound address %v has no port", addr)
+			}
+			if got := addr.IP.To4() != nil; got != tt.wantIPv4 {
+				t.Errorf("bound %v, want IPv4: %v", addr, tt.wantIPv4)
+			}
+
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				conn.Write([]byte("hi"))
+				conn.Close()
+			}()
+			conn, err := net.Dial(tt.dial, net.JoinHostPort(tt.dialHost, strconv.Itoa(addr.Port)))
+			if err != nil {
+				t.Fatalf("dialing the reported port: %v", err)
+			}
+			defer conn.Close()
+			if b, _ := io.ReadAll(conn); string(b) != "hi" {
+				t.Errorf("read %q from the listener", b)
+			}
+		})
+	}
+}
+
+func TestListenBadNetwork(t *testing.T) {
+	for _, network := range []string{"udp", "unix", ""} {
+		if l, _, err := Listen(network, ":0"); err == nil {
+			l.Close()
+			t.Errorf("Listen(%q) succeeded", network)
+		}
+	}
+}
+
+// "tcp6" is IPv6 only, even on the wildcard address.
+func TestListenTCP6IsIPv6Only(t *testing.T) {
+	l, addr, err := Listen("tcp6", "[::]:0")
+	if err != nil {
+		t.Skipf("no IPv6 here: %v", err)
+	}
+	defer l.Close()
+
+	if conn, err := net.Dial("tcp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(addr.Port))); err == nil {
+		conn.Close()
+		t.Error("an IPv4 client got through to a tcp6 listener")
+	}
+}
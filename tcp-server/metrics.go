@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// metrics is what the Server counted over its lifetime, for the report logged on shutdown.
+type metrics struct {
+	start    time.Time
+	conns    atomic.Int64 // connections handed to a handler
+	rejected atomic.Int64 // turned away with a 503, over MaxOpenConns or dropped from the pool queue
+	peak     atomic.Int64 // most connections open at once
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// LogReport logs the end-of-run summary, call it once Shutdown has returned.
+func (s *Server) LogReport() {
+	m := &s.metrics
+	s.init()
+	s.Logger.Info("shutdown report",
+		"connections", m.conns.Load(),
+		"rejected", m.rejected.Load(),
+		"uptime", time.Since(m.start).Round(time.Millisecond),
+		"peak_concurrency", m.peak.Load(),
+		"bytes_in", m.bytesIn.Load(),
+		"bytes_out", m.bytesOut.Load(),
+	)
+}
+
+// raisePeak sets peak to n if n is higher, retrying when another connection moved it in between.
+func raisePeak(peak *atomic.Int64, n int64) {
+	for {
+		current := peak.Load()
+		if n <= current || peak.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// countingConn adds up the bytes going through the connection, whichever handler reads and writes it.
+type countingConn struct {
+	net.Conn
+	in, out *atomic.Int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.in.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.out.Add(int64(n))
+	return n, err
+}
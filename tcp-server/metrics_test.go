@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownReport(t *testing.T) {
+	var logs syncBuffer
+	release := make(chan struct{})
+	var entered sync.WaitGroup
+	srv := &Server{MaxOpenConns: 3, Logger: slog.New(slog.NewJSONHandler(&logs, nil)), Handler: func(ctx context.Context, conn net.Conn) {
+		buf := make([]byte, 5)
+		io.ReadFull(conn, buf)
+		entered.Done()
+		<-release
+		conn.Write([]byte("0123456789"))
+		conn.Close()
+	}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		return conn
+	}
+	var conns []net.Conn
+	entered.Add(3)
+	for range 3 {
+		conn := dial()
+		conn.Write([]byte("hello"))
+		conns = append(conns, conn)
+	}
+	entered.Wait()
+	extra := dial() // past MaxOpenConns
+	io.ReadAll(extra)
+	extra.Close()
+
+	close(release)
+	for _, conn := range conns {
+		io.ReadAll(conn)
+		conn.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.LogReport()
+	lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+	var report map[string]any
+	if err := json.Unmarshal(lines[len(lines)-1], &report); err != nil {
+		t.Fatalf("%v: %s", err, logs.Bytes())
+	}
+	want := map[string]float64{"connections": 3, "rejected": 1, "peak_concurrency": 3, "bytes_in": 15, "bytes_out": 30}
+	for k, v := range want {
+		if report[k] != v {
+			t.Errorf("%s = %v, want %v", k, report[k], v)
+		}
+	}
+	if report["msg"] != "shutdown report" {
+		t.Errorf("msg = %v", report["msg"])
+	}
+}
+
+// syncBuffer is a bytes.Buffer the server's goroutines can log to at the same time.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
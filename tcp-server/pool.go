@@ -0,0 +1,210 @@
+/*
+A fixed size worker pool.
+
+Instead of one goroutine per connection, a fixed number of workers pull jobs off a buffered channel.
+- The number of workers caps how much work runs at once, no matter how many clients show up.
+- The channel buffer is the queue, once it's full Submit blocks, which slows the accept loop down (backpressure).
+- On Shutdown the queue is drained: queued jobs still run until the drain deadline, whatever is left after that
+  gets its drop func instead, e.g. a connection is told 503 rather than silently hung up on.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+var ErrPoolClosed = errors.New("tcp-server: pool is shut down")
+
+type job struct {
+	run  func()
+	drop func() // called instead of run when the pool gives up on the job, may be nil
+}
+
+type Pool struct {
+	Logger logger.Logger // for panicking jobs, nil means logger.Default(). Set it before the first Submit. [5]
+
+	jobs      chan job
+	wg        sync.WaitGroup
+	abandoned atomic.Bool // set once the drain deadline has passed, queued jobs are dropped from then on
+
+	mu      sync.RWMutex // guards closed
+	closed  bool
+	quit    chan struct{}  // closed by Shutdown, wakes up the Submits blocked on a full queue
+	sending sync.WaitGroup // Submits past the closed check, Shutdown waits for them before closing jobs [2]
+}
+
+func NewPool(workers, queueSize int) *Pool {
+	p := &Pool{jobs: make(chan job, queueSize), quit: make(chan struct{})}
+	for range workers {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for j := range p.jobs { // [1]
+		if p.abandoned.Load() {
+			p.dropJob(j)
+			continue
+		}
+		p.run(j.run)
+	}
+}
+
+func (p *Pool) dropJob(j job) {
+	if j.drop != nil {
+		p.run(j.drop)
+	}
+}
+
+// run executes job, surviving a panic in it. [3]
+func (p *Pool) run(job func()) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Or(p.Logger).Error("job panicked", "panic", rec, "stack", string(debug.Stack()))
+		}
+	}()
+	job()
+}
+
+// Submit queues run for a worker, blocking while the queue is full, or until the pool is shut down.
+func (p *Pool) Submit(run func()) error {
+	return p.SubmitOrDrop(run, nil)
+}
+
+// SubmitOrDrop is Submit with a fallback, drop is called instead of run if the job is
+// still queued when Shutdown's deadline passes.
+func (p *Pool) SubmitOrDrop(run, drop func()) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrPoolClosed
+	}
+	p.sending.Add(1)
+	p.mu.RUnlock()
+	defer p.sending.Done()
+
+	select {
+	case p.jobs <- job{run: run, drop: drop}:
+		return nil
+	case <-p.quit:
+		return ErrPoolClosed
+	}
+}
+
+// QueueDepth is how many jobs are waiting for a worker right now.
+func (p *Pool) QueueDepth() int {
+	return len(p.jobs)
+}
+
+// DepthAlert watches the queue depth for signs the pool is the wrong size.
+// A queue that stays long means jobs wait for workers, more workers (or a faster job) would help.
+// A queue that stays empty means workers sit idle, fewer would do.
+type DepthAlert struct {
+	Interval  time.Duration   // how often the depth is sampled
+	Sustain   int             // consecutive samples past a watermark before its callback fires, so a burst doesn't count
+	High, Low int             // watermarks, OnHigh fires for depth >= High, OnLow for depth <= Low
+	OnHigh    func(depth int) // nil disables the high watermark
+	OnLow     func(depth int) // nil disables the low watermark
+}
+
+// WatchDepth samples the queue depth until ctx is done. Each callback fires once when the depth has stayed
+// past its watermark for Sustain samples, and again only after the depth has gone back between the watermarks.
+func (p *Pool) WatchDepth(ctx context.Context, a DepthAlert) {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	sustain := max(a.Sustain, 1)
+	var highRun, lowRun int // consecutive samples past each watermark
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		depth := p.QueueDepth()
+		if depth >= a.High {
+			highRun++
+		} else {
+			highRun = 0
+		}
+		if depth <= a.Low {
+			lowRun++
+		} else {
+			lowRun = 0
+		}
+
+		if a.OnHigh != nil && highRun == sustain { // == rather than >=, so it fires once per stretch
+			a.OnHigh(depth)
+		}
+		if a.OnLow != nil && lowRun == sustain {
+			a.OnLow(depth)
+		}
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the workers to finish everything already queued.
+// If that takes longer than ctx allows, the jobs still queued are dropped, and Shutdown returns ctx.Err()
+// while the jobs already running carry on in the background. [4]
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	first := !p.closed
+	if first {
+		p.closed = true
+		close(p.quit)
+	}
+	p.mu.Unlock()
+	if first {
+		p.sending.Wait() // quick, quit has unblocked them all
+		close(p.jobs)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.abandoned.Store(true)
+		for j := range p.jobs { // the workers may all be stuck on long jobs, so drop the rest ourselves
+			p.dropJob(j)
+		}
+		return ctx.Err()
+	}
+}
+
+/*
+[1] : Ranging over a channel keeps receiving until it's closed *and* empty,
+			so closing jobs in Shutdown still lets the workers finish the queued jobs before they exit.
+
+[2] : Sending on a closed channel panics, so jobs is only closed once no Submit can be sending on it anymore:
+			the closed flag stops new ones, and the ones already past it are counted in sending.
+			Holding the lock across the send instead would let one Submit stuck on a full queue block Shutdown
+			(and, since a waiting writer blocks new readers of a RWMutex, every other Submit) until a worker frees a slot.
+
+[3] : An unrecovered panic in any goroutine crashes the whole process, not just the worker.
+			And even if it only ended the worker, a fixed size pool would be one worker short for good.
+			So the panic is logged and the worker moves on to the next job. The job's deferred calls still run
+			while the panic unwinds, which is what closes the connection of the request that blew up.
+
+[4] : Dropping has to happen before Shutdown returns, main exits right after it and a job left in the channel
+			would vanish with the process, along with the connection it was holding.
+
+[5] : The workers only read Logger after receiving a job, and a channel send happens before the matching receive,
+			so a Logger set before Submit is seen by the workers without a lock.
+*/
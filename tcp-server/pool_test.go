@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+func TestPoolRunsJobsAndSurvivesPanics(t *testing.T) {
+	p := NewPool(2, 4)
+	p.Logger = logger.Nop
+
+	var ran atomic.Int32
+	for i := range 10 {
+		if err := p.Submit(func() {
+			if i == 3 {
+				panic("boom")
+			}
+			ran.Add(1)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := ran.Load(); got != 9 {
+		t.Errorf("%d jobs ran, want 9", got)
+	}
+	if err := p.Submit(func() {}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit after Shutdown = %v, want ErrPoolClosed", err)
+	}
+}
+
+// A Submit blocked on a full queue must neither hold up Shutdown nor stay blocked once it's called.
+func TestPoolShutdownUnblocksSubmit(t *testing.T) {
+	p := NewPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(func() { close(started); <-release }) // the only worker is busy
+	<-started
+	p.Submit(func() {}) // the queue is full
+
+	submitted := make(chan error, 1)
+	go func() { submitted <- p.Submit(func() {}) }()
+	time.Sleep(20 * time.Millisecond) // let it block on the full queue
+
+	shutdown := make(chan error, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	go func() { shutdown <- p.Shutdown(ctx) }()
+
+	select {
+	case err := <-submitted:
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("blocked Submit returned %v, want ErrPoolClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Submit still blocked after Shutdown")
+	}
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown = %v, want the deadline exceeded, a job was still running", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown blocked by the Submit")
+	}
+	close(release)
+}
+
+func TestPoolDropsQueuedJobsPastTheDeadline(t *testing.T) {
+	p := NewPool(1, 4)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	p.Submit(func() { close(started); <-release })
+	<-started
+
+	var ran, dropped atomic.Int32
+	for range 3 {
+		p.SubmitOrDrop(func() { ran.Add(1) }, func() { dropped.Add(1) })
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if ran.Load() != 0 || dropped.Load() != 3 {
+		t.Errorf("ran %d, dropped %d, want 0 and 3", ran.Load(), dropped.Load())
+	}
+}
+
+// Connections still queued when the server shuts down get a 503, whether the worker frees up in time or not.
+func TestServerShutdownAnswersQueuedConns(t *testing.T) {
+	tests := []struct {
+		name         string
+		busyQuits    bool // the busy handler returns once the server shuts down, instead of past the pool's deadline
+		wantShutdown error
+	}{
+		{"worker frees up", true, nil},
+		{"worker stays busy", false, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewPool(1, 4)
+			pool.Logger = logger.Nop
+			release := make(chan struct{})
+			defer close(release)
+			busy := make(chan struct{})
+			srv := &Server{Pool: pool, Logger: logger.Nop, Handler: func(ctx context.Context, conn net.Conn) {
+				defer conn.Close()
+				close(busy) // only the first connection ever reaches a handler
+				if tt.busyQuits {
+					<-ctx.Done()
+				} else {
+					<-release
+				}
+			}}
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go srv.Serve(l)
+
+			dial := func() net.Conn {
+				conn, err := net.Dial("tcp", l.Addr().String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				conn.SetDeadline(time.Now().Add(2 * time.Second))
+				return conn
+			}
+			first := dial()
+			defer first.Close()
+			<-busy
+			var queued []net.Conn
+			for range 3 {
+				conn := dial()
+				defer conn.Close()
+				queued = append(queued, conn)
+			}
+			for deadline := time.Now().Add(time.Second); pool.QueueDepth() < 3; {
+				if time.Now().After(deadline) {
+					t.Fatalf("only %d connections queued", pool.QueueDepth())
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			srv.Shutdown(ctx)
+			if err := pool.Shutdown(ctx); !errors.Is(err, tt.wantShutdown) {
+				t.Errorf("pool Shutdown = %v, want %v", err, tt.wantShutdown)
+			}
+
+			for i, conn := range queued {
+				res, err := io.ReadAll(conn)
+				if err != nil {
+					t.Fatalf("queued connection %d: %v", i, err)
+				}
+				if !strings.HasPrefix(string(res), "HTTP/1.1 503") {
+					t.Errorf("queued connection %d got %q, want a 503", i, res)
+				}
+			}
+		})
+	}
+}
+
+func TestWatchDepth(t *testing.T) {
+	tests := []struct {
+		name              string
+		queued            int // jobs left waiting behind the one busy worker
+		wantHigh, wantLow []int
+	}{
+		{"saturated", 8, []int{8}, nil},
+		{"at the high watermark", 4, []int{4}, nil},
+		{"idle", 0, nil, []int{0}},
+		{"in between", 2, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool(1, 8)
+			release := make(chan struct{})
+			started := make(chan struct{})
+			p.Submit(func() { close(started); <-release })
+			<-started
+			for range tt.queued {
+				p.Submit(func() {})
+			}
+			defer p.Shutdown(context.Background())
+			defer close(release)
+
+			var mu sync.Mutex
+			var high, low []int
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond) // 20 samples
+			defer cancel()
+			p.WatchDepth(ctx, DepthAlert{
+				Interval: 5 * time.Millisecond,
+				Sustain:  3,
+				High:     4,
+				Low:      0,
+				OnHigh:   func(depth int) { mu.Lock(); high = append(high, depth); mu.Unlock() },
+				OnLow:    func(depth int) { mu.Lock(); low = append(low, depth); mu.Unlock() },
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(high, tt.wantHigh) || !slices.Equal(low, tt.wantLow) {
+				t.Errorf("OnHigh got %v, OnLow got %v, want %v and %v, once per sustained stretch", high, low, tt.wantHigh, tt.wantLow)
+			}
+		})
+	}
+}
+
+// A watermark fires again after the depth has come back, each stretch past it counts once.
+func TestWatchDepthRearms(t *testing.T) {
+	p := NewPool(1, 8)
+	defer p.Shutdown(context.Background())
+
+	var lows atomic.Int32
+	highs := make(chan int, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.WatchDepth(ctx, DepthAlert{
+		Interval: 5 * time.Millisecond, Sustain: 2, High: 3, Low: 0,
+		OnHigh: func(depth int) { highs <- depth },
+		OnLow:  func(int) { lows.Add(1) },
+	})
+	waitLows := func(n int32) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); lows.Load() < n; time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("OnLow fired %d times, want %d", lows.Load(), n)
+			}
+		}
+	}
+	waitLows(1) // the pool starts out idle
+
+	for round := int32(1); round <= 2; round++ {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		p.Submit(func() { close(started); <-release })
+		<-started
+		for range 3 {
+			p.Submit(func() {})
+		}
+		select {
+		case <-highs:
+		case <-time.After(time.Second):
+			t.Fatalf("round %d: OnHigh didn't fire", round)
+		}
+		close(release) // the queue drains, the depth goes back to 0
+		waitLows(round + 1)
+	}
+
+	time.Sleep(50 * time.Millisecond) // an idle queue must not keep firing
+	if got := lows.Load(); got != 3 {
+		t.Errorf("OnLow fired %d times, want 3: at the start and after each drain", got)
+	}
+	if len(highs) != 0 {
+		t.Errorf("OnHigh fired %d extra times", len(highs))
+	}
+}
@@ -0,0 +1,167 @@
+/*
+A tiny subset of RESP, the REdis Serialization Protocol.
+
+Clients send every command as an array of bulk strings, e.g. SET foo bar:
+	*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n
+- "*3" : an array of 3 elements.
+- "$3" : a bulk string of 3 bytes, followed by the bytes themselves.
+The first byte of every reply tells its type:
+	+OK\r\n              simple string
+	-ERR message\r\n     error
+	$3\r\nbar\r\n        bulk string
+	$-1\r\n              null bulk string (GET on a missing key)
+
+Supported commands: PING [message], ECHO message, SET key value, GET key, VERSION.
+Try it with: redis-cli -p 4221 (after go run ./tcp-server -mode resp)
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+const maxBulkLen = 512 << 10 // refuse bulk strings over 512kb instead of allocating whatever the client asks for
+
+type RESPHandler struct {
+	mu   sync.RWMutex
+	data map[string]string
+}
+
+func NewRESPHandler() *RESPHandler {
+	return &RESPHandler{data: make(map[string]string)}
+}
+
+// Serve answers commands on conn until the client disconnects or ctx is canceled.
+func (h *RESPHandler) Serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for ctx.Err() == nil {
+		args, err := readCommand(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				writeError(w, err.Error())
+				w.Flush() // [1]
+				logger.FromContext(ctx).Warn("resp: bad command", "remote", conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+
+		h.exec(w, args)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *RESPHandler) exec(w *bufio.Writer, args []string) {
+	if len(args) == 0 {
+		writeError(w, "ERR empty command")
+		return
+	}
+
+	name := strings.ToUpper(args[0])
+	switch {
+	case name == "PING" && len(args) == 1:
+		w.WriteString("+PONG\r\n")
+	case name == "PING" && len(args) == 2:
+		writeBulk(w, args[1])
+	case name == "ECHO" && len(args) == 2:
+		writeBulk(w, args[1])
+	case name == "SET" && len(args) == 3:
+		h.mu.Lock()
+		h.data[args[1]] = args[2]
+		h.mu.Unlock()
+		w.WriteString("+OK\r\n")
+	case name == "GET" && len(args) == 2:
+		h.mu.RLock()
+		value, ok := h.data[args[1]]
+		h.mu.RUnlock()
+		if !ok {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		writeBulk(w, value)
+	case name == "VERSION" && len(args) == 1:
+		writeBulk(w, versionReply())
+	case name == "PING" || name == "ECHO" || name == "SET" || name == "GET" || name == "VERSION":
+		writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// readCommand reads one "*N" array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readHeader(r, '*')
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for range n {
+		size, err := readHeader(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		if size > maxBulkLen {
+			return nil, fmt.Errorf("ERR bulk string of %d bytes is too large", size)
+		}
+
+		buf := make([]byte, size+2) // the data plus its trailing \r\n
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if string(buf[size:]) != "\r\n" {
+			return nil, errors.New("ERR protocol error: bulk string not terminated by CRLF")
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+// readHeader reads a "<prefix><number>\r\n" line, like "*3" or "$5".
+func readHeader(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		if line != "" && errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF // EOF halfway through a command is not a clean disconnect
+		}
+		return 0, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) < 2 || line[0] != prefix {
+		return 0, fmt.Errorf("ERR protocol error: expected '%c', got %q", prefix, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("ERR protocol error: invalid length %q", line[1:])
+	}
+	return n, nil
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + msg + "\r\n")
+}
+
+/*
+[1] : Replies are buffered in a bufio.Writer and only sent on Flush, one per command,
+			so pipelined commands don't turn into one tiny write (syscall) per reply fragment.
+*/
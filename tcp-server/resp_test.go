@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRESPHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string // raw RESP, possibly several pipelined commands
+		want    string // every reply, concatenated
+	}{
+		{"ping", "*1\r\n$4\r\nPING\r\n", "+PONG\r\n"},
+		{"ping with message", "*2\r\n$4\r\nping\r\n$2\r\nhi\r\n", "$2\r\nhi\r\n"},
+		{"echo", "*2\r\n$4\r\nECHO\r\n$11\r\nhello world\r\n", "$11\r\nhello world\r\n"},
+		{"set then get", "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n", "+OK\r\n$3\r\nbar\r\n"},
+		{"get miss", "*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n", "$-1\r\n"},
+		{"empty value", "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n", "+OK\r\n$0\r\n\r\n"},
+		{"unknown command", "*1\r\n$5\r\nFLUSH\r\n", "-ERR unknown command 'FLUSH'\r\n"},
+		{"wrong arity", "*1\r\n$3\r\nGET\r\n", "-ERR wrong number of arguments for 'get' command\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := respRoundTrip(t, tt.command, len(tt.want)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRESPHandlerProtocolError(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string // prefix of the error reply
+	}{
+		{"not an array", "PING\r\n", "-ERR protocol error: expected '*'"},
+		{"bad length", "*1\r\n$x\r\n", "-ERR protocol error: invalid length"},
+		{"missing CRLF", "*1\r\n$4\r\nPINGxx", "-ERR protocol error: bulk string not terminated by CRLF"},
+		{"too large", "*1\r\n$999999999\r\n", "-ERR bulk string of 999999999 bytes is too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := respRoundTrip(t, tt.command, -1); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("got %q, want it to start with %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// respRoundTrip sends raw to a fresh RESPHandler over a net.Pipe and returns the first n bytes it replies,
+// or everything until it hangs up if n is negative.
+func respRoundTrip(t *testing.T, raw string, n int) string {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	go NewRESPHandler().Serve(context.Background(), server)
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	go client.Write([]byte(raw)) // net.Pipe is unbuffered, the write only returns once the handler has read it all
+	var reply []byte
+	var err error
+	if n < 0 {
+		reply, err = io.ReadAll(client)
+	} else {
+		reply = make([]byte, n)
+		_, err = io.ReadFull(client, reply)
+	}
+	if err != nil {
+		t.Fatalf("reading the reply: %v", err)
+	}
+	return string(reply)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+var ErrServerClosed = errors.New("tcp-server: Server closed")
+
+// shutdownWriteGrace is how long handlers have to write a last response once the server shuts down.
+const shutdownWriteGrace = time.Second
+
+// Server owns the accept loop.
+// Zero values are usable, the backoff bounds fall back to the same 5ms..1s net/http uses.
+// Without a Pool every connection gets its own goroutine, without a Handler connections are served by do.
+type Server struct {
+	AcceptBackoffMin time.Duration
+	AcceptBackoffMax time.Duration
+	Pool             *Pool
+	Handler          func(ctx context.Context, conn net.Conn)
+
+	// TCP options for accepted connections, zero values keep Go's defaults. [2]
+	NoDelay         bool
+	KeepAlivePeriod time.Duration
+
+	MaxConnBytes int64     // total bytes a client may send over one connection, 0 means no limit
+	Throttle     int       // bytes per second each connection may read and write, 0 means no limit
+	DumpBytes    int       // hex dump up to this many bytes each client sends, for debugging, 0 disables it
+	DumpTo       io.Writer // where the dumps go, nil means os.Stderr
+
+	// MaxOpenConns is a soft limit on open connections, 0 means no limit. [5]
+	// Past it new connections are turned away straight away, instead of waiting for Accept to start failing with EMFILE.
+	MaxOpenConns int
+	openConns    atomic.Int64
+
+	Latency *LatencyRecorder // if set, records how long each connection took, from Accept to its handler returning
+	Logger  logger.Logger    // nil means logger.Default()
+	metrics metrics
+
+	initOnce sync.Once
+	ctx      context.Context // server-wide, every connection context derives from it [1]
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup // in-flight connections
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+func (s *Server) init() {
+	s.initOnce.Do(func() {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+		s.Logger = logger.Or(s.Logger)
+		s.metrics.start = time.Now()
+	})
+}
+
+func (s *Server) Serve(l net.Listener) error {
+	s.init()
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	var delay time.Duration // current backoff, reset after every successful Accept
+
+	for {
+		s.Logger.Debug("waiting for a client to connect")
+
+		conn, err := l.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return ErrServerClosed
+			}
+			if !isTemporary(err) {
+				return err
+			}
+			delay = s.nextBackoff(delay)
+			s.Logger.Warn("accept error, retrying", "err", err, "delay", delay)
+			time.Sleep(delay) // [3]
+			continue
+		}
+		delay = 0
+		accepted := time.Now() // latency counts from here, the time spent queued for a worker included
+
+		s.Logger.Debug("client connected", "remote", conn.RemoteAddr(), "since_start", time.Since(start))
+
+		if s.MaxOpenConns > 0 && s.openConns.Load() >= int64(s.MaxOpenConns) {
+			s.Logger.Warn("too many open connections, rejecting", "open", s.MaxOpenConns, "remote", conn.RemoteAddr())
+			reject(conn)
+			s.metrics.rejected.Add(1)
+			continue
+		}
+
+		if err := s.applyTCPOptions(conn); err != nil {
+			s.Logger.Warn("setting tcp options", "err", err)
+		}
+
+		raisePeak(&s.metrics.peak, s.openConns.Add(1))
+		s.wg.Add(1)
+		if s.Pool == nil {
+			go s.handleRecover(conn, accepted) // remove go keyword to make this function call single threaded
+			continue
+		}
+		err = s.Pool.SubmitOrDrop(
+			func() { s.handle(conn, accepted) },
+			func() { s.drop(conn) }, // still queued when the pool's drain deadline passed
+		)
+		if err != nil {
+			s.Logger.Warn("rejecting connection", "err", err)
+			conn.Close()
+			s.openConns.Add(-1)
+			s.wg.Done()
+		}
+	}
+}
+
+// OpenConns is the number of connections currently being served.
+func (s *Server) OpenConns() int64 {
+	return s.openConns.Load()
+}
+
+// handleRecover is handle for connections served by their own goroutine, which have no pool worker
+// to recover their panics for them.
+func (s *Server) handleRecover(conn net.Conn, accepted time.Time) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.Logger.Error("handler panicked", "remote", conn.RemoteAddr(), "panic", rec, "stack", string(debug.Stack()))
+		}
+	}()
+	s.handle(conn, accepted)
+}
+
+// reject closes a connection we won't serve, telling the client why first if it can take it right away.
+func reject(conn net.Conn) {
+	conn.SetWriteDeadline(time.Now().Add(100 * time.Millisecond)) // never let a slow client stall the accept loop
+	conn.Write([]byte("HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nRetry-After: 1\r\nContent-Length: 0\r\n\r\n"))
+	conn.Close()
+}
+
+// drop is handle for a connection that never got to a handler.
+func (s *Server) drop(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.openConns.Add(-1)
+	reject(conn)
+	s.metrics.rejected.Add(1)
+}
+
+// handle serves conn, accepted is when Serve accepted it.
+func (s *Server) handle(conn net.Conn, accepted time.Time) {
+	defer s.wg.Done()
+	defer s.openConns.Add(-1)
+	defer conn.Close() // handlers close it themselves, this covers the ones that panic or forget
+
+	if s.ctx.Err() != nil { // it waited in the pool's queue while we started shutting down
+		reject(conn)
+		s.metrics.rejected.Add(1)
+		return
+	}
+	s.metrics.conns.Add(1)
+
+	if s.Latency != nil {
+		defer func() { s.Latency.Record(time.Since(accepted)) }()
+	}
+
+	ctx, cancel := context.WithCancel(logger.NewContext(s.ctx, s.Logger)) // handlers log through logger.FromContext
+	defer cancel()
+
+	// Reads and writes don't know about contexts, so unblock them by expiring the deadline. [4]
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Unix(1, 0))
+		conn.SetWriteDeadline(time.Now().Add(shutdownWriteGrace))
+	})
+	defer stop()
+
+	conn = &countingConn{Conn: conn, in: &s.metrics.bytesIn, out: &s.metrics.bytesOut}
+	if s.DumpBytes > 0 {
+		w := s.DumpTo
+		if w == nil {
+			w = os.Stderr
+		}
+		conn = &dumpConn{Conn: conn, w: w, remaining: s.DumpBytes}
+	}
+	if s.Throttle > 0 {
+		conn = NewThrottledConn(conn, s.Throttle)
+	}
+	if s.MaxConnBytes > 0 {
+		conn = &budgetConn{Conn: conn, remaining: s.MaxConnBytes}
+	}
+
+	if s.Handler != nil {
+		s.Handler(ctx, conn)
+		return
+	}
+	do(ctx, conn)
+}
+
+// tcpOptions is the part of *net.TCPConn we configure. Connections that aren't TCP
+// (e.g. from a unix socket listener) don't implement it and are left alone.
+type tcpOptions interface {
+	SetNoDelay(noDelay bool) error
+	SetKeepAlive(keepalive bool) error
+	SetKeepAlivePeriod(d time.Duration) error
+}
+
+func (s *Server) applyTCPOptions(conn net.Conn) error {
+	tc, ok := conn.(tcpOptions)
+	if !ok {
+		return nil
+	}
+	if s.NoDelay {
+		if err := tc.SetNoDelay(true); err != nil {
+			return err
+		}
+	}
+	if s.KeepAlivePeriod > 0 {
+		if err := tc.SetKeepAlive(true); err != nil {
+			return err
+		}
+		return tc.SetKeepAlivePeriod(s.KeepAlivePeriod)
+	}
+	return nil
+}
+
+// Shutdown stops accepting new connections, cancels the context of the ones in flight
+// and waits for their handlers to return, or for ctx to be done, whichever comes first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.init()
+	s.cancel()
+
+	s.mu.Lock()
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) nextBackoff(delay time.Duration) time.Duration {
+	minDelay, maxDelay := s.AcceptBackoffMin, s.AcceptBackoffMax
+	if minDelay <= 0 {
+		minDelay = 5 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = time.Second
+	}
+	if delay == 0 {
+		return minDelay
+	}
+	return min(delay*2, maxDelay)
+}
+
+// isTemporary reports whether an Accept error is worth retrying.
+// Running out of file descriptors or a client that hung up before we accepted it
+// says nothing about the health of the listener itself.
+func isTemporary(err error) bool {
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var te interface{ Temporary() bool }
+	return errors.As(err, &te) && te.Temporary()
+}
+
+/*
+[1] : Canceling the server context cancels every per-connection context derived from it,
+			so a single s.cancel() in Shutdown reaches all handlers at once.
+
+[2] : NoDelay disables Nagle's algorithm, which holds back small writes hoping to merge them into one packet.
+			That saves bandwidth but adds latency to request/response protocols like ours.
+			Go actually sets TCP_NODELAY on every TCP connection already, NoDelay just makes it explicit.
+			Keepalive probes detect peers that vanished without closing (cable pulled, NAT dropped the mapping).
+			Go enables them with a 15s period by default, the same applies to the connections behind net/http.
+
+[3] : Retrying immediately on EMFILE just spins the CPU, no descriptor has been freed yet.
+			Doubling the delay (capped) gives in-flight connections time to finish and close.
+			net/http's Server.Serve does exactly the same thing internally, which is why the HTTP server
+			in server/ doesn't need any of this.
+
+[4] : A deadline in the past makes any blocked conn.Read return immediately with a timeout error.
+			Writes get a short grace period instead, so handlers can still tell the client they're shutting down.
+			context.AfterFunc runs the function in its own goroutine once ctx is done, stop() unregisters it.
+
+[5] : Every connection holds a file descriptor, and the process only gets so many (ulimit -n).
+			Once they run out Accept fails, but so does everything else needing one: opening files, dialing a database...
+			Keeping the soft limit below the hard one leaves headroom for those. Check the limit with "ulimit -n".
+*/
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
+)
+
+// startServer runs srv on a loopback port until the test ends.
+func startServer(t testing.TB, srv *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.Logger == nil {
+		srv.Logger = logger.Nop
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		if srv.Pool != nil {
+			srv.Pool.Shutdown(ctx)
+		}
+	})
+	return l.Addr().String()
+}
+
+// With one worker, the connections behind the first one wait in the queue, and that wait is part of their latency.
+func TestLatencyIncludesQueueWait(t *testing.T) {
+	const work = 50 * time.Millisecond
+	srv := &Server{
+		Pool:    NewPool(1, 4),
+		Latency: NewLatencyRecorder(16),
+		Handler: func(ctx context.Context, conn net.Conn) {
+			time.Sleep(work)
+			conn.Close()
+		},
+	}
+	addr := startServer(t, srv)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			conn.Read(make([]byte, 1)) // until the handler closes it
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx) // waits for the handlers, and their Record calls
+
+	p := srv.Latency.Percentiles(0, 100)
+	if p[0] < work {
+		t.Errorf("fastest connection took %v, less than the handler's %v", p[0], work)
+	}
+	if p[1] < 3*work-10*time.Millisecond {
+		t.Errorf("slowest connection took %v, want about %v: 2 handlers' worth of queue wait plus its own", p[1], 3*work)
+	}
+}
+
+// fakeListener hands out the results queued in accepts, then fails for good.
+type fakeListener struct {
+	accepts []acceptResult
+	closed  chan struct{}
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func (l *fakeListener) Accept() (net.Conn, error) {
+	if len(l.accepts) == 0 {
+		return nil, errors.New("listener gone")
+	}
+	next := l.accepts[0]
+	l.accepts = l.accepts[1:]
+	return next.conn, next.err
+}
+func (l *fakeListener) Close() error   { return nil }
+func (l *fakeListener) Addr() net.Addr { return &net.TCPAddr{} }
+
+func TestServeRetriesTemporaryErrors(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	l := &fakeListener{accepts: []acceptResult{
+		{err: syscall.EMFILE},
+		{err: fmt.Errorf("accept: %w", syscall.ECONNABORTED)},
+		{conn: server},
+	}}
+
+	handled := make(chan struct{})
+	srv := &Server{
+		Logger:           logger.Nop,
+		AcceptBackoffMin: time.Millisecond,
+		Handler:          func(ctx context.Context, conn net.Conn) { close(handled); conn.Close() },
+	}
+	err := srv.Serve(l)
+	if err == nil || err.Error() != "listener gone" {
+		t.Errorf("Serve = %v, want the permanent error", err)
+	}
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("the connection accepted after the temporary errors was never handled")
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	var s Server
+	want := []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond}
+	var d time.Duration
+	for i, w := range want {
+		if d = s.nextBackoff(d); d != w {
+			t.Fatalf("step %d: %v, want %v", i, d, w)
+		}
+	}
+	for range 20 {
+		d = s.nextBackoff(d)
+	}
+	if d != time.Second {
+		t.Errorf("backoff grew to %v, want it capped at 1s", d)
+	}
+
+	s = Server{AcceptBackoffMin: time.Millisecond, AcceptBackoffMax: 3 * time.Millisecond}
+	if d := s.nextBackoff(s.nextBackoff(s.nextBackoff(0))); d != 3*time.Millisecond {
+		t.Errorf("custom bounds: %v, want 3ms", d)
+	}
+}
+
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{syscall.EMFILE, true},
+		{syscall.ENFILE, true},
+		{&net.OpError{Op: "accept", Err: syscall.ECONNRESET}, true},
+		{net.ErrClosed, false},
+		{errors.New("something else"), false},
+	}
+	for _, tt := range tests {
+		if got := isTemporary(tt.err); got != tt.want {
+			t.Errorf("isTemporary(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// Shutdown cancels the connection contexts, a handler waiting on one returns right away.
+func TestShutdownCancelsHandlers(t *testing.T) {
+	started := make(chan struct{})
+	returned := make(chan time.Duration, 1)
+	srv := &Server{Handler: func(ctx context.Context, conn net.Conn) {
+		close(started)
+		begin := time.Now()
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Second):
+		}
+		returned <- time.Since(begin)
+	}}
+	addr := startServer(t, srv)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v, the handler didn't return", err)
+	}
+	if took := <-returned; took > 500*time.Millisecond {
+		t.Errorf("handler took %v to notice the shutdown", took)
+	}
+}
+
+// optionConn is a net.Conn implementing tcpOptions, recording the calls instead of making them.
+type optionConn struct {
+	net.Conn
+	calls []string
+}
+
+func (c *optionConn) SetNoDelay(b bool) error {
+	c.calls = append(c.calls, fmt.Sprint("SetNoDelay(", b, ")"))
+	return nil
+}
+func (c *optionConn) SetKeepAlive(b bool) error {
+	c.calls = append(c.calls, fmt.Sprint("SetKeepAlive(", b, ")"))
+	return nil
+}
+func (c *optionConn) SetKeepAlivePeriod(d time.Duration) error {
+	c.calls = append(c.calls, fmt.Sprint("SetKeepAlivePeriod(", d, ")"))
+	return nil
+}
+
+func TestApplyTCPOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		noDelay         bool
+		keepAlivePeriod time.Duration
+		want            string
+	}{
+		{"defaults", false, 0, ""},
+		{"no delay", true, 0, "SetNoDelay(true)"},
+		{"keepalive", false, 30 * time.Second, "SetKeepAlive(true) SetKeepAlivePeriod(30s)"},
+		{"both", true, time.Minute, "SetNoDelay(true) SetKeepAlive(true) SetKeepAlivePeriod(1m0s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			conn := &optionConn{Conn: server}
+			handled := make(chan struct{})
+			srv := &Server{
+				Logger:          logger.Nop,
+				NoDelay:         tt.noDelay,
+				KeepAlivePeriod: tt.keepAlivePeriod,
+				Handler:         func(ctx context.Context, conn net.Conn) { close(handled); conn.Close() },
+			}
+			srv.Serve(&fakeListener{accepts: []acceptResult{{conn: conn}}})
+			<-handled
+
+			if got := strings.Join(conn.calls, " "); got != tt.want {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTCPOptionsNonTCP(t *testing.T) {
+	server, client := net.Pipe() // not a *net.TCPConn, like a unix socket's connections
+	defer client.Close()
+	srv := &Server{NoDelay: true, KeepAlivePeriod: time.Minute}
+	if err := srv.applyTCPOptions(server); err != nil {
+		t.Errorf("applyTCPOptions on a non TCP conn = %v, want nil", err)
+	}
+
+	handled := make(chan struct{})
+	srv.Logger = logger.Nop
+	srv.Handler = func(ctx context.Context, conn net.Conn) { close(handled); conn.Close() }
+	srv.Serve(&fakeListener{accepts: []acceptResult{{conn: server}}})
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("the non TCP connection was never handled")
+	}
+}
+
+// do, mid fake delay when the server shuts down, answers 503 instead of hanging on or hanging up.
+func TestDoAnswers503OnShutdown(t *testing.T) {
+	srv := &Server{} // fakeDelay is 8s, the test would time out if do waited it out
+	addr := startServer(t, srv)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	time.Sleep(50 * time.Millisecond) // let do read the request and start waiting
+
+	go srv.Shutdown(context.Background())
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	res, _ := io.ReadAll(conn)
+	if !strings.HasPrefix(string(res), "HTTP/1.1 503") {
+		t.Errorf("got %q, want a 503", res)
+	}
+}
+
+func TestMaxOpenConnsRejects(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	srv := &Server{MaxOpenConns: 2, Handler: func(ctx context.Context, conn net.Conn) {
+		started <- struct{}{}
+		<-release
+		conn.Write([]byte("served"))
+		conn.Close()
+	}}
+	addr := startServer(t, srv)
+
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		return conn
+	}
+	var held []net.Conn
+	for range 2 {
+		held = append(held, dial())
+		<-started
+	}
+
+	extra := dial()
+	defer extra.Close()
+	res, err := io.ReadAll(extra) // answered and closed straight away, not queued
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(res), "HTTP/1.1 503") || !strings.Contains(string(res), "Retry-After:") {
+		t.Errorf("connection past the limit got %q, want a 503 with Retry-After", res)
+	}
+
+	close(release)
+	for _, conn := range held {
+		if b, _ := io.ReadAll(conn); string(b) != "served" {
+			t.Errorf("connection within the limit got %q", b)
+		}
+		conn.Close()
+	}
+
+	// room again once they're gone
+	deadline := time.Now().Add(time.Second)
+	for srv.OpenConns() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	again := dial()
+	defer again.Close()
+	if b, _ := io.ReadAll(again); string(b) != "served" {
+		t.Errorf("connection after the others closed got %q", b)
+	}
+}
+
+func TestServerSurvivesHandlerPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		pool *Pool
+	}{
+		{"one worker pool", NewPool(1, 4)},
+		{"goroutine per connection", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.pool != nil {
+				tt.pool.Logger = logger.Nop
+			}
+			srv := &Server{Pool: tt.pool, Handler: func(ctx context.Context, conn net.Conn) {
+				buf := make([]byte, 16)
+				n, _ := conn.Read(buf)
+				if string(buf[:n]) == "panic" {
+					panic("boom")
+				}
+				conn.Write([]byte("ok"))
+				conn.Close()
+			}}
+			addr := startServer(t, srv)
+
+			send := func(msg string) string {
+				conn, err := net.Dial("tcp", addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(2 * time.Second))
+				conn.Write([]byte(msg))
+				b, err := io.ReadAll(conn)
+				if err != nil {
+					t.Fatalf("%s: %v", msg, err) // a timeout here means the connection was never closed
+				}
+				return string(b)
+			}
+			for i, msg := range []string{"hello", "panic", "hello", "panic", "panic", "hello"} {
+				want := "ok"
+				if msg == "panic" {
+					want = "" // closed without an answer
+				}
+				if got := send(msg); got != want {
+					t.Errorf("request %d (%s): got %q, want %q", i, msg, got, want)
+				}
+			}
+		})
+	}
+}
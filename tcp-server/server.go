@@ -19,46 +19,163 @@ NOTE:
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"net"
+	"os"
+	"os/signal"
 	"time"
+
+	"github.com/amitsuthar69/go-backend/internal/logger"
 )
 
 var start = time.Now()
 
-func do(conn net.Conn) {
+var fakeDelay = time.Second * 8
+
+func do(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
 	buffer := make([]byte, 1024) // this buffer is a temporary storage of 1kb in memory to hold the data being read.
 
-	_, err := conn.Read(buffer) // conn.Read() returns number of bytes read and error.
-	if err != nil {
-		log.Fatal("error reading from connection: ", err)
+	n, err := conn.Read(buffer) // conn.Read() returns number of bytes read and error.
+	if errors.Is(err, ErrByteBudgetExceeded) {
+		conn.Write([]byte("HTTP/1.1 413 Content Too Large\r\nConnection: close\r\n\r\n"))
+		if bc, ok := conn.(*budgetConn); ok {
+			bc.drain()
+		}
+		return
+	}
+	if n == 0 {
+		if ctx.Err() == nil { // on shutdown, an interrupted read is expected
+			logger.FromContext(ctx).Warn("error reading from connection", "err", err)
+		}
+		return
 	}
 
-	time.Sleep(time.Second * 8) // fake delay
+	ctx, stop := watchDisconnect(ctx, conn)
+	defer stop()
+
+	select { // fake delay, cut short if the server shuts down or the client hangs up
+	case <-time.After(fakeDelay):
+	case <-ctx.Done():
+	}
+
+	if context.Cause(ctx) == errClientGone {
+		logger.FromContext(ctx).Info("client disconnected before its response was ready, dropping it")
+		return
+	}
+
+	if ctx.Err() != nil { // we've got a request but we're shutting down, say so instead of just hanging up
+		conn.Write([]byte("HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nContent-Length: 0\r\n\r\n"))
+		return
+	}
 
 	conn.Write([]byte("HTTP/1.1 200 OK\r\n\r\nHey Client!\r\n")) // responding with a HTTP Status code 200 OK
-	defer conn.Close()
 }
 
 func main() {
-	l, err := net.Listen("tcp", ":4221") // creating a TCP listener which listens on port 4221
+	bench := flag.Int("bench", 0, "open N concurrent connections against an in-process server, report throughput and exit")
+	addr := flag.String("addr", ":4221", "address to listen on, use :0 for any free port")
+	network := flag.String("network", "tcp", "tcp4, tcp6 or tcp (dual-stack)")
+	mode := flag.String("mode", "http", "protocol to speak: http, json, resp, line, template, or auto to detect it per connection")
+	tmpl := flag.String("template", "{{method}} {{path}} at {{now}}\n", "response body for -mode template, see template.go for the placeholders")
+	workers := flag.Int("workers", 0, "size of the worker pool, 0 spins off a goroutine per connection")
+	noDelay := flag.Bool("nodelay", false, "explicitly disable Nagle's algorithm on accepted connections")
+	maxConnBytes := flag.Int64("max-conn-bytes", 0, "close connections that send more than this many bytes in total, 0 means no limit")
+	throttle := flag.Int("throttle", 0, "limit each connection to this many bytes per second each way, 0 means no limit")
+	maxOpenConns := flag.Int("max-open-conns", 0, "reject new connections once this many are open, 0 means no limit")
+	latencyLog := flag.Duration("latency-log", 0, "log latency percentiles at this interval, 0 disables it")
+	keepAlive := flag.Duration("keepalive", 0, "TCP keepalive period for accepted connections, 0 keeps the default")
+	flag.DurationVar(&fakeDelay, "delay", fakeDelay, "fake processing delay per request")
+	dump := flag.Int("dump", 0, "log a hex dump of the first N bytes each client sends, 0 disables it")
+	verbose := flag.Bool("verbose", false, "also log debug messages, like every accepted connection")
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	lg := logger.New(os.Stderr, level)
+
+	var pool *Pool
+	if *workers > 0 {
+		pool = NewPool(*workers, *workers*4)
+		pool.Logger = lg
+	}
+
+	if *bench > 0 {
+		if err := runBenchmark(*bench, pool); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	l, bound, err := Listen(*network, *addr) // creating a TCP listener which listens on port 4221 by default
 	if err != nil {
-		log.Fatal("Failed binding to port 4221", err.Error())
+		log.Fatal("Failed binding to ", *addr, ": ", err.Error())
+	}
+	lg.Info("listening", "addr", bound, "network", *network)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if pool != nil {
+		go pool.WatchDepth(ctx, DepthAlert{
+			Interval: time.Second,
+			Sustain:  10,
+			High:     *workers * 3, // the queue is 4x the workers, 3/4 full
+			OnHigh: func(depth int) {
+				lg.Warn("pool queue has stayed long, consider more -workers", "depth", depth, "workers", *workers)
+			},
+		})
+	}
+
+	srv := &Server{Pool: pool, NoDelay: *noDelay, KeepAlivePeriod: *keepAlive, MaxConnBytes: *maxConnBytes, MaxOpenConns: *maxOpenConns, Throttle: *throttle, DumpBytes: *dump, Logger: lg}
+	if *latencyLog > 0 {
+		srv.Latency = NewLatencyRecorder(1024)
+		go srv.Latency.LogEvery(ctx, lg, *latencyLog)
 	}
 
-	for {
-		fmt.Println("waiting for a client to connect...")
+	switch *mode {
+	case "http":
+	case "json":
+		srv.Handler = ServeJSON
+	case "resp":
+		srv.Handler = NewRESPHandler().Serve
+	case "line":
+		srv.Handler = (&LineHandler{}).Serve
+	case "template":
+		srv.Handler = ServeTemplate(*tmpl, "")
+	case "auto":
+		srv.Handler = (&Sniffer{HTTP: do, RESP: NewRESPHandler().Serve, Line: (&LineHandler{}).Serve}).Serve
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
 
-		conn, err := l.Accept()
-		if err != nil {
-			log.Fatal("Error accepting connection: ", err.Error())
+	go func() {
+		if err := srv.Serve(l); !errors.Is(err, ErrServerClosed) {
+			log.Fatal("Error accepting connection: ", err)
 		}
+	}()
 
-		fmt.Println("client connected at: ", time.Since(start))
+	<-ctx.Done()
+	lg.Info("shutting down...")
 
-		go do(conn) // remove go keyword to make this function call single threaded
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		lg.Error("shutdown", "err", err)
 	}
+	if pool != nil {
+		if err := pool.Shutdown(shutdownCtx); err != nil {
+			lg.Error("pool shutdown", "err", err)
+		}
+	}
+	srv.LogReport()
 }
 
 /*
@@ -76,4 +193,6 @@ Well, what happens when 'n' shoots up?
 Honce we can't just have threads spinning up every now and then.
 - We need to limit maximum numbers of thread we create.
 - This is exactly what thread pool solves.
+
+Run with -workers N to hand connections to the fixed size pool in pool.go instead.
 */
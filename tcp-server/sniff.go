@@ -0,0 +1,93 @@
+/*
+Protocol sniffing: one port, several protocols.
+
+Every protocol we speak has the client talk first, and their first bytes don't look alike:
+- HTTP starts with a method: "GET /", "POST /"...
+- RESP starts with '*', the array of the first command.
+- anything else is taken for the line protocol.
+bufio.Reader.Peek lets us look at those bytes without consuming them, so the chosen handler
+still reads the connection from its very first byte.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+)
+
+var httpMethods = [][]byte{
+	[]byte("GET "), []byte("HEAD "), []byte("POST "), []byte("PUT "), []byte("PATCH "),
+	[]byte("DELETE "), []byte("OPTIONS "), []byte("CONNECT "), []byte("TRACE "),
+}
+
+// peekedConn is a connection whose first bytes were already read into r, reads go through r first.
+type peekedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// Sniffer dispatches each connection to the handler for the protocol it speaks.
+type Sniffer struct {
+	HTTP, RESP, Line func(ctx context.Context, conn net.Conn)
+}
+
+func (s *Sniffer) Serve(ctx context.Context, conn net.Conn) {
+	r := bufio.NewReader(conn)
+	proto, err := sniff(r)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	pc := &peekedConn{Conn: conn, r: r}
+	switch proto {
+	case "http":
+		s.HTTP(ctx, pc)
+	case "resp":
+		s.RESP(ctx, pc)
+	default:
+		s.Line(ctx, pc)
+	}
+}
+
+func sniff(r *bufio.Reader) (string, error) {
+	if _, err := r.Peek(1); err != nil { // blocks until the client has sent something
+		return "", err
+	}
+
+	for {
+		buf, _ := r.Peek(r.Buffered()) // whatever has arrived so far, without waiting for more
+		if buf[0] == '*' {
+			return "resp", nil
+		}
+
+		partial := false
+		for _, m := range httpMethods {
+			if bytes.HasPrefix(buf, m) {
+				return "http", nil
+			}
+			if len(buf) < len(m) && bytes.HasPrefix(m, buf) {
+				partial = true // "GE" could still become "GET ", wait for another byte [1]
+			}
+		}
+		if !partial || bytes.IndexByte(buf, '\n') >= 0 {
+			return "line", nil
+		}
+		if _, err := r.Peek(len(buf) + 1); err != nil {
+			return "line", nil // the client stopped sending, let the line handler deal with what's there
+		}
+	}
+}
+
+/*
+[1] : Peek(n) blocks until n bytes have arrived. Asking for a fixed 8 bytes upfront would hang forever
+			on a client that sends "PING\n" (5 bytes) and waits for the reply, so we only wait for more
+			while the bytes so far could still be the start of an HTTP method.
+*/
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSniffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string // sent one after the other, to split the first bytes over several reads
+		want   string
+	}{
+		{"http", []string{"GET / HTTP/1.1\r\nHost: x\r\n\r\n"}, "http"},
+		{"http post", []string{"POST /posts HTTP/1.1\r\n\r\n"}, "http"},
+		{"http split method", []string{"GE", "T / HTTP/1.1\r\n\r\n"}, "http"},
+		{"resp", []string{"*1\r\n$4\r\nPING\r\n"}, "resp"},
+		{"line", []string{"PING\n"}, "line"},
+		{"line starting like a method", []string{"GETX\n"}, "line"},
+		{"short line", []string{"GE\n"}, "line"}, // has a newline, no HTTP method is that short
+		{"lowercase is not http", []string{"get / HTTP/1.1\r\n\r\n"}, "line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var all string
+			for _, w := range tt.writes {
+				all += w
+			}
+
+			got := make(chan [2]string, 1) // the protocol picked, and what its handler read
+			handler := func(proto string) func(context.Context, net.Conn) {
+				return func(ctx context.Context, conn net.Conn) {
+					buf := make([]byte, len(all))
+					n, _ := io.ReadFull(conn, buf)
+					got <- [2]string{proto, string(buf[:n])}
+					conn.Close()
+				}
+			}
+			s := &Sniffer{HTTP: handler("http"), RESP: handler("resp"), Line: handler("line")}
+
+			server, client := net.Pipe()
+			defer client.Close()
+			go s.Serve(context.Background(), server)
+			for _, w := range tt.writes {
+				client.Write([]byte(w))
+				time.Sleep(5 * time.Millisecond) // make sure the sniffer sees the pieces apart
+			}
+
+			select {
+			case g := <-got:
+				if g[0] != tt.want {
+					t.Errorf("routed to %s, want %s", g[0], tt.want)
+				}
+				if g[1] != all {
+					t.Errorf("handler read %q, want every byte sent, %q", g[1], all)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no handler got the connection")
+			}
+		})
+	}
+}
+
+func TestSnifferClientGone(t *testing.T) {
+	called := make(chan struct{}, 1)
+	h := func(ctx context.Context, conn net.Conn) { called <- struct{}{} }
+	s := &Sniffer{HTTP: h, RESP: h, Line: h}
+
+	server, client := net.Pipe()
+	client.Close() // hangs up without sending anything
+	s.Serve(context.Background(), server)
+	select {
+	case <-called:
+		t.Error("a handler was called for a connection that sent nothing")
+	default:
+	}
+}
+
+func TestLineHandler(t *testing.T) {
+	tests := []struct {
+		send, want string
+	}{
+		{"PING\n", "PONG\n"},
+		{"ping\r\n", "PONG\n"},
+		{"ECHO hello world\n", "hello world\n"},
+		{"\nPING\n", "PONG\n"}, // empty lines get no reply
+		{"NOPE\n", "ERR unknown command \"NOPE\"\n"},
+		{"PING\nQUIT\n", "PONG\nBYE\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.send, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			go (&LineHandler{}).Serve(context.Background(), server)
+			client.SetDeadline(time.Now().Add(2 * time.Second))
+			go client.Write([]byte(tt.send))
+
+			buf := make([]byte, len(tt.want))
+			if _, err := io.ReadFull(client, buf); err != nil {
+				t.Fatal(err)
+			}
+			if string(buf) != tt.want {
+				t.Errorf("got %q, want %q", buf, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+/*
+A fixed response with a few blanks filled in per request, for load testing: every response is the same size
+and costs next to nothing to produce, so what we measure is the server and the network, not a handler.
+
+	go run ./tcp-server -mode template -template '{"method":"{{method}}","path":"{{path}}","at":"{{now}}"}'
+
+Placeholders are {{method}}, {{path}} and {{now}} (RFC 3339, UTC), anything else is sent as written.
+*/
+
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// ServeTemplate answers every request with body, its placeholders filled in from the request.
+func ServeTemplate(body, contentType string) func(ctx context.Context, conn net.Conn) {
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	return func(ctx context.Context, conn net.Conn) {
+		serveRequests(ctx, conn, func(req *Request) *Response {
+			r := strings.NewReplacer( // [1]
+				"{{method}}", req.Method,
+				"{{path}}", req.Path,
+				"{{now}}", time.Now().UTC().Format(time.RFC3339),
+			)
+			return &Response{Status: 200, Headers: map[string]string{"Content-Type": contentType}, Body: []byte(r.Replace(body))}
+		})
+	}
+}
+
+/*
+[1] : The values are pasted in as they are, a {{path}} inside a JSON or HTML template isn't escaped.
+			Fine for a load testing target, not for anything a browser renders.
+*/
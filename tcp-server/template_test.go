@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeTemplate(t *testing.T) {
+	tests := []struct {
+		name, body, contentType string
+		request                 string
+		want                    string // with {{now}} left in, it's checked separately
+		wantContentType         string
+	}{
+		{"every placeholder", `{"method":"{{method}}","path":"{{path}}","at":"{{now}}"}`, "application/json",
+			"POST /posts?page=2 HTTP/1.1\r\nContent-Length: 0\r\n\r\n",
+			`{"method":"POST","path":"/posts?page=2","at":"{{now}}"}`, "application/json"},
+		{"repeated placeholder", "{{path}} {{path}}", "", "GET /a HTTP/1.1\r\n\r\n", "/a /a", "text/plain; charset=utf-8"},
+		{"unknown placeholder", "{{user}} {{ method }}", "", "GET / HTTP/1.1\r\n\r\n", "{{user}} {{ method }}", "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startServer(t, &Server{Handler: ServeTemplate(tt.body, tt.contentType)})
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			before := time.Now().UTC().Truncate(time.Second)
+			conn.Write([]byte(tt.request))
+			res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(res.Body)
+			got := string(b)
+
+			if res.StatusCode != 200 || res.Header.Get("Content-Type") != tt.wantContentType {
+				t.Errorf("got %d %q, want 200 %q", res.StatusCode, res.Header.Get("Content-Type"), tt.wantContentType)
+			}
+			if prefix, suffix, ok := strings.Cut(tt.want, "{{now}}"); ok && strings.HasPrefix(got, prefix) && strings.HasSuffix(got, suffix) {
+				stamp := strings.TrimSuffix(strings.TrimPrefix(got, prefix), suffix)
+				at, err := time.Parse(time.RFC3339, stamp)
+				if err != nil || at.Before(before) || at.After(time.Now()) || !strings.HasSuffix(stamp, "Z") {
+					t.Errorf("{{now}} became %q, want the current UTC time in RFC 3339", stamp)
+				}
+				got = prefix + "{{now}}" + suffix
+			}
+			if got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Simulating a slow network.
+
+ThrottledConn caps how fast bytes go in and out of a connection with a token bucket per direction:
+- the bucket fills up at rate tokens (bytes) per second, up to a small burst.
+- every byte read or written takes a token, when the bucket runs dry we sleep until it has refilled enough.
+Try it with a big response, or with: go run ./tcp-server -mode line -throttle 10
+*/
+
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+type ThrottledConn struct {
+	net.Conn
+	read, write *tokenBucket
+}
+
+// NewThrottledConn limits both reads and writes on conn to bytesPerSec each.
+func NewThrottledConn(conn net.Conn, bytesPerSec int) *ThrottledConn {
+	return &ThrottledConn{
+		Conn:  conn,
+		read:  newTokenBucket(bytesPerSec),
+		write: newTokenBucket(bytesPerSec),
+	}
+}
+
+func (c *ThrottledConn) Read(b []byte) (int, error) {
+	if len(b) > c.read.burst {
+		b = b[:c.read.burst] // never take in more than one burst at a time
+	}
+	n, err := c.Conn.Read(b)
+	c.read.take(n) // the bytes are in already, so pay for them afterwards, which delays the next read
+	return n, err
+}
+
+func (c *ThrottledConn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		chunk := b[:min(len(b), c.write.burst)] // chunked, or one big write would leave in a single burst
+		c.write.take(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+type tokenBucket struct {
+	rate  float64 // tokens added per second
+	burst int     // bucket size
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	burst := max(rate/10, 1) // a tenth of a second worth, so the pace looks smooth rather than once-a-second bursts
+	return &tokenBucket{rate: float64(rate), burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// take removes n tokens, sleeping for as long as it takes the bucket to cover any shortfall. [1]
+func (b *tokenBucket) take(n int) {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, float64(b.burst))
+	b.last = now
+	b.tokens -= float64(n)
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / b.rate * float64(time.Second)))
+	}
+}
+
+/*
+[1] : The bucket is allowed to go negative, that's the debt the sleep pays off. The refill on the next take
+			then starts from below zero, so the time spent sleeping isn't handed out a second time as fresh tokens.
+*/
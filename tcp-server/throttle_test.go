@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestThrottledConn(t *testing.T) {
+	const rate = 10_000 // bytes per second, so the initial burst is 1000 bytes
+	payload := bytes.Repeat([]byte("x"), 5000)
+	want := time.Duration(float64(len(payload)-rate/10) / rate * float64(time.Second)) // everything past the burst, 400ms
+
+	tests := []struct {
+		name string
+		// copy moves payload from one end of a pipe to the other, throttling one direction
+		copy func(server, client net.Conn) ([]byte, error)
+	}{
+		{"write", func(server, client net.Conn) ([]byte, error) {
+			go func() {
+				NewThrottledConn(server, rate).Write(payload)
+				server.Close()
+			}()
+			return io.ReadAll(client)
+		}},
+		{"read", func(server, client net.Conn) ([]byte, error) {
+			go func() {
+				client.Write(payload)
+				client.Close()
+			}()
+			return io.ReadAll(NewThrottledConn(server, rate))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+
+			begin := time.Now()
+			got, err := tt.copy(server, client)
+			elapsed := time.Since(begin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Fatalf("got %d bytes, want the %d sent", len(got), len(payload))
+			}
+			if elapsed < want*3/4 || elapsed > want*2 {
+				t.Errorf("%d bytes at %d B/s took %v, want about %v", len(payload), rate, elapsed, want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	b := newTokenBucket(1000) // burst of 100
+	begin := time.Now()
+	b.take(100)
+	if elapsed := time.Since(begin); elapsed > 10*time.Millisecond {
+		t.Errorf("taking a full burst slept %v, want no wait", elapsed)
+	}
+	begin = time.Now()
+	b.take(50) // the bucket is empty, 50 tokens take 50ms to come back
+	if elapsed := time.Since(begin); elapsed < 40*time.Millisecond || elapsed > 150*time.Millisecond {
+		t.Errorf("taking 50 from an empty bucket slept %v, want about 50ms", elapsed)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// BuildInfo is what the VERSION command reports.
+type BuildInfo struct {
+	Version   string // module version, "(devel)" for a go run or a build from a checkout
+	Revision  string // vcs commit the binary was built from, if the go tool recorded it [1]
+	GoVersion string
+}
+
+var readBuildInfo = sync.OnceValue(func() BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{Version: "unknown", GoVersion: "unknown"}
+	}
+	b := BuildInfo{Version: info.Main.Version, GoVersion: info.GoVersion}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			b.Revision = s.Value
+		}
+	}
+	return b
+})
+
+// versionReply is the one line reply to VERSION, e.g. "tcp-server (devel) rev 50eef14 go1.22.0 uptime 1m2s".
+func versionReply() string {
+	b := readBuildInfo()
+	rev := ""
+	if b.Revision != "" {
+		rev = " rev " + b.Revision[:min(len(b.Revision), 7)]
+	}
+	return fmt.Sprintf("tcp-server %s%s %s uptime %s", b.Version, rev, b.GoVersion, time.Since(start).Round(time.Second))
+}
+
+/*
+[1] : go build stamps the revision when building inside a git checkout (see -buildvcs),
+			go run and go test don't, so it's often empty during development.
+*/
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(context.Context, net.Conn)
+		send    string
+		read    func(*bufio.Reader) (string, error)
+	}{
+		{"line", (&LineHandler{}).Serve, "VERSION\n", func(r *bufio.Reader) (string, error) {
+			return r.ReadString('\n')
+		}},
+		{"line lowercase", (&LineHandler{}).Serve, "version\r\n", func(r *bufio.Reader) (string, error) {
+			return r.ReadString('\n')
+		}},
+		{"resp", NewRESPHandler().Serve, "*1\r\n$7\r\nVERSION\r\n", readBulk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			go tt.handler(context.Background(), server)
+			client.SetDeadline(time.Now().Add(2 * time.Second))
+			go client.Write([]byte(tt.send))
+
+			reply, err := tt.read(bufio.NewReader(client))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(reply, "tcp-server ") || !strings.Contains(reply, " "+runtime.Version()+" ") {
+				t.Errorf("reply = %q, want the server name and %s", reply, runtime.Version())
+			}
+			if !strings.Contains(reply, " uptime ") {
+				t.Errorf("reply = %q, want the uptime", reply)
+			}
+		})
+	}
+}
+
+// readBulk reads one RESP bulk string and returns its payload.
+func readBulk(r *bufio.Reader) (string, error) {
+	head, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(head, "$"), "\r\n"))
+	if err != nil || !strings.HasPrefix(head, "$") {
+		return "", fmt.Errorf("not a bulk string: %q", head)
+	}
+	payload := make([]byte, n+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", err
+	}
+	return string(payload[:n]), nil
+}